@@ -0,0 +1,56 @@
+package main
+
+import "fmt"
+
+// Assignment is a statement such as x = 1 + 2, which binds x for the
+// statements after it.
+type Assignment struct {
+	Name        string
+	Value       Expression
+	Position    Position
+	EndPosition Position
+}
+
+func (*Assignment) exprNode() {}
+
+func (a *Assignment) Pos() Position {
+	return a.Position
+}
+
+func (a *Assignment) End() Position {
+	return a.EndPosition
+}
+
+func (a *Assignment) String() string {
+	return fmt.Sprintf("%s = %s", a.Name, a.Value)
+}
+
+// parseAssignment parses the rest of an assignment to target, whose "=" is the
+// next token.
+func parseAssignment(p *parser, target *Identifier) (Expression, error) {
+	p.Lex()
+	value, err := parseExpression(p)
+	if err != nil {
+		return nil, err
+	}
+	return &Assignment{Name: target.Name, Value: value, Position: target.Position, EndPosition: p.end}, nil
+}
+
+// parseLet parses let name = value, which is the same assignment as
+// name = value.
+func parseLet(p *parser) (Expression, error) {
+	pos, _, _ := p.Lex()
+
+	namePos, tok, name := p.Lex()
+	if tok != IDENT {
+		return nil, expected(namePos, tok, "variable name after let")
+	}
+	if err := expect(p, ASSIGN); err != nil {
+		return nil, err
+	}
+	value, err := parseExpression(p)
+	if err != nil {
+		return nil, err
+	}
+	return &Assignment{Name: name, Value: value, Position: pos, EndPosition: p.end}, nil
+}
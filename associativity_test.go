@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestAssociativity(t *testing.T) {
+	tests := []struct {
+		input string
+		tree  string
+		want  string
+	}{
+		{"10 - 3 - 2", "((10 - 3) - 2)", "5"},
+		{"16 / 4 / 2", "((16 / 4) / 2)", "2"},
+		{"100 % 7 % 3", "((100 % 7) % 3)", "2"},
+		{"10 - 2 + 3", "((10 - 2) + 3)", "11"},
+		{"1 - 2 + 3 - 4", "(((1 - 2) + 3) - 4)", "-2"},
+		{"2 * 3 / 4", "((2 * 3) / 4)", "1"},
+		{"100 / 10 * 2", "((100 / 10) * 2)", "20"},
+		{"7 % 4 * 2", "((7 % 4) * 2)", "6"},
+		{"20 / 2 % 3", "((20 / 2) % 3)", "1"},
+		{"10 - 6 / 2 - 1", "((10 - (6 / 2)) - 1)", "6"},
+		{"2 ^ 3 ^ 2", "(2 ^ (3 ^ 2))", "512"},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.input)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.input, err)
+			continue
+		}
+		if got := expr.String(); got != tt.tree {
+			t.Errorf("Parse(%q) = %s, want %s", tt.input, got, tt.tree)
+		}
+		v, err := EvaluateValue(expr, nil)
+		if err != nil || v.String() != tt.want {
+			t.Errorf("EvaluateValue(%q) = %s, %v, want %s", tt.input, v, err, tt.want)
+		}
+	}
+}
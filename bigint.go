@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// factorial computes n! for a non-negative integer. In BigInt mode the result
+// is exact, up to n = maxBigFactorial; otherwise results that overflow int
+// are an error.
+func (ev *evaluator) factorial(v Value) (Value, error) {
+	if v.Kind != IntValue && v.Kind != BigValue {
+		return Value{}, fmt.Errorf("cannot take factorial of %s %s", v.Kind, v.Quote())
+	}
+	n := v.bigInt()
+	if n.Sign() < 0 {
+		return Value{}, fmt.Errorf("factorial of negative number %s", n)
+	}
+	if !n.IsInt64() || ev.opts.BigInt && n.Int64() > maxBigFactorial {
+		return Value{}, fmt.Errorf("factorial of %s is too large", n)
+	}
+
+	if ev.opts.BigInt {
+		return Value{Kind: BigValue, Big: new(big.Int).MulRange(1, n.Int64())}, nil
+	}
+
+	result := 1
+	for i := 2; i <= int(n.Int64()); i++ {
+		if result > math.MaxInt/i {
+			return Value{}, fmt.Errorf("factorial of %s overflows int", n)
+		}
+		result *= i
+	}
+	return Value{Kind: IntValue, Int: result}, nil
+}
+
+// isBigOperand reports whether v can take part in big-integer arithmetic with
+// other, which requires at least one of the two to already be big.
+func isBigOperand(v, other Value) bool {
+	return (v.Kind == BigValue || v.Kind == IntValue) && (v.Kind == BigValue || other.Kind == BigValue)
+}
+
+// bigInt returns v's integer payload as a *big.Int.
+func (v Value) bigInt() *big.Int {
+	if v.Kind == BigValue {
+		return v.Big
+	}
+	return big.NewInt(int64(v.Int))
+}
+
+// maxBigFactorial bounds the argument of an exact factorial. 10000! has
+// 35660 digits and takes a few milliseconds; each further factor makes the
+// result longer and the multiplication slower.
+const maxBigFactorial = 10000
+
+// maxBigExponentBits bounds the exponent of an exact power, whose result
+// would otherwise be limited only by memory.
+const maxBigExponentBits = 20
+
+func applyBigOp(op Token, left, right *big.Int) (Value, error) {
+	result := new(big.Int)
+	switch op {
+	case ADD:
+		result.Add(left, right)
+	case SUB:
+		result.Sub(left, right)
+	case MUL:
+		result.Mul(left, right)
+	case DIV:
+		if right.Sign() == 0 {
+			return Value{}, fmt.Errorf("division by zero")
+		}
+		// Quo truncates toward zero, matching int division.
+		result.Quo(left, right)
+	case MOD:
+		if right.Sign() == 0 {
+			return Value{}, fmt.Errorf("modulo by zero")
+		}
+		// Rem takes the sign of the dividend, matching int modulo.
+		result.Rem(left, right)
+	case POW:
+		if right.Sign() < 0 {
+			return Value{}, fmt.Errorf("negative exponent %s", right)
+		}
+		if right.BitLen() > maxBigExponentBits {
+			return Value{}, fmt.Errorf("exponent %s is too large", right)
+		}
+		result.Exp(left, right, nil)
+	default:
+		return Value{}, fmt.Errorf("unknown operator")
+	}
+	return Value{Kind: BigValue, Big: result}, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestFactorial(t *testing.T) {
+	if v, err := Eval("20!"); err != nil || v != 2432902008176640000 {
+		t.Errorf("20! = %d, %v, want 2432902008176640000", v, err)
+	}
+	if v, err := Eval("0! + 3!"); err != nil || v != 7 {
+		t.Errorf("0! + 3! = %d, %v, want 7", v, err)
+	}
+
+	v, err := EvaluateValueWith(mustParse(t, "25!"), nil, EvalOptions{BigInt: true})
+	if err != nil || v.Kind != BigValue || v.String() != "15511210043330985984000000" {
+		t.Errorf("25! in big mode = %s %s, %v, want 15511210043330985984000000", v.Kind, v, err)
+	}
+}
+
+func TestFactorialErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"21!", "factorial of 21 overflows int"},
+		{"(-1)!", "factorial of negative number -1"},
+		{"2.5!", "cannot take factorial of float 2.5"},
+	}
+	for _, tt := range tests {
+		if _, err := Eval(tt.input); err == nil || err.Error() != tt.want {
+			t.Errorf("Eval(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+	if _, err := EvaluateValueWith(mustParse(t, "(-1)!"), nil, EvalOptions{BigInt: true}); err == nil {
+		t.Errorf("(-1)! in big mode did not fail")
+	}
+}
+
+func TestBigFactorialLimit(t *testing.T) {
+	opts := EvalOptions{BigInt: true}
+	v, err := EvaluateValueWith(mustParse(t, "10000!"), nil, opts)
+	if err != nil || v.Kind != BigValue || len(v.String()) != 35660 {
+		t.Errorf("10000! in big mode = %d digits, %v, want 35660", len(v.String()), err)
+	}
+	for _, input := range []string{"10001!", "10000000!", "9223372036854775807!"} {
+		_, err := EvaluateValueWith(mustParse(t, input), nil, opts)
+		if want := "factorial of " + input[:len(input)-1] + " is too large"; err == nil || err.Error() != want {
+			t.Errorf("%s in big mode error = %v, want %q", input, err, want)
+		}
+	}
+}
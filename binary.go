@@ -0,0 +1,539 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// The binary encoding of a tree is its nodes in prefix order, each a tag
+// byte followed by its fields and then its children. Integers are varints,
+// strings and lists are length-prefixed, and every expression ends with its
+// start and end positions. Operators are stored as their Token values, which
+// are not stable between versions of this package, so the encoding is only
+// suitable for caches that are discarded when the program is rebuilt.
+const (
+	binaryNil byte = iota
+	binaryProgram
+	binaryBinary
+	binaryUnary
+	binaryInteger
+	binaryFloat
+	binaryString
+	binaryIdentifier
+	binaryCast
+	binaryRange
+	binaryList
+	binaryCall
+	binaryAssignment
+	binaryFunction
+	binaryNary
+)
+
+// errTruncated is returned by UnmarshalNode when the data ends mid-node.
+var errTruncated = errors.New("unexpected end of data")
+
+// MarshalNode encodes n, and all of its children, in a compact binary form
+// that UnmarshalNode decodes into an Equal tree with the same positions.
+func MarshalNode(n Node) ([]byte, error) {
+	var e encoder
+	if err := e.node(n); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// UnmarshalNode decodes data produced by MarshalNode.
+func UnmarshalNode(data []byte) (Node, error) {
+	d := decoder{buf: data}
+	var n Node
+	if len(data) > 0 && data[0] == binaryProgram {
+		d.buf = d.buf[1:]
+		exprs, err := d.exprs()
+		if err != nil {
+			return nil, fmt.Errorf("UnmarshalNode: %w", err)
+		}
+		n = &Program{Statements: exprs}
+	} else {
+		expr, err := d.expr()
+		if err != nil {
+			return nil, fmt.Errorf("UnmarshalNode: %w", err)
+		}
+		n = expr
+	}
+	if len(d.buf) > 0 {
+		return nil, fmt.Errorf("UnmarshalNode: %d bytes of trailing data", len(d.buf))
+	}
+	return n, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler using MarshalNode.
+func (be *BinaryExpression) MarshalBinary() ([]byte, error) {
+	return MarshalNode(be)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (ne *NaryExpression) MarshalBinary() ([]byte, error) {
+	return MarshalNode(ne)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (ue *UnaryExpression) MarshalBinary() ([]byte, error) {
+	return MarshalNode(ue)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (il *IntegerLiteral) MarshalBinary() ([]byte, error) {
+	return MarshalNode(il)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (fl *FloatLiteral) MarshalBinary() ([]byte, error) {
+	return MarshalNode(fl)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (ce *CastExpression) MarshalBinary() ([]byte, error) {
+	return MarshalNode(ce)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (re *RangeExpression) MarshalBinary() ([]byte, error) {
+	return MarshalNode(re)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (id *Identifier) MarshalBinary() ([]byte, error) {
+	return MarshalNode(id)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (sl *StringLiteral) MarshalBinary() ([]byte, error) {
+	return MarshalNode(sl)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (el *ExpressionList) MarshalBinary() ([]byte, error) {
+	return MarshalNode(el)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (ce *CallExpression) MarshalBinary() ([]byte, error) {
+	return MarshalNode(ce)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (a *Assignment) MarshalBinary() ([]byte, error) {
+	return MarshalNode(a)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (fd *FunctionDefinition) MarshalBinary() ([]byte, error) {
+	return MarshalNode(fd)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p *Program) MarshalBinary() ([]byte, error) {
+	return MarshalNode(p)
+}
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) node(n Node) error {
+	switch n := n.(type) {
+	case *Program:
+		e.tag(binaryProgram)
+		return e.exprs(n.Statements)
+	case *BinaryExpression:
+		e.tag(binaryBinary)
+		e.int(int(n.Op))
+		if err := e.node(n.Left); err != nil {
+			return err
+		}
+		if err := e.node(n.Right); err != nil {
+			return err
+		}
+	case *NaryExpression:
+		e.tag(binaryNary)
+		e.int(int(n.Op))
+		if err := e.exprs(n.Operands); err != nil {
+			return err
+		}
+	case *UnaryExpression:
+		e.tag(binaryUnary)
+		e.int(int(n.Op))
+		if n.Postfix {
+			e.int(1)
+		} else {
+			e.int(0)
+		}
+		if err := e.node(n.Operand); err != nil {
+			return err
+		}
+	case *IntegerLiteral:
+		e.tag(binaryInteger)
+		e.int(n.Value)
+		e.string(n.Literal)
+	case *FloatLiteral:
+		e.tag(binaryFloat)
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(n.Value))
+		e.string(n.Literal)
+	case *StringLiteral:
+		e.tag(binaryString)
+		e.string(n.Value)
+	case *Identifier:
+		e.tag(binaryIdentifier)
+		e.string(n.Name)
+	case *CastExpression:
+		e.tag(binaryCast)
+		e.string(n.Type)
+		if err := e.node(n.Operand); err != nil {
+			return err
+		}
+	case *RangeExpression:
+		e.tag(binaryRange)
+		if err := e.node(n.From); err != nil {
+			return err
+		}
+		if err := e.node(n.To); err != nil {
+			return err
+		}
+	case *ExpressionList:
+		e.tag(binaryList)
+		if err := e.exprs(n.Elements); err != nil {
+			return err
+		}
+	case *CallExpression:
+		e.tag(binaryCall)
+		e.string(n.Function)
+		if err := e.exprs(n.Arguments); err != nil {
+			return err
+		}
+	case *Assignment:
+		e.tag(binaryAssignment)
+		e.string(n.Name)
+		if err := e.node(n.Value); err != nil {
+			return err
+		}
+	case *FunctionDefinition:
+		e.tag(binaryFunction)
+		e.string(n.Name)
+		e.int(len(n.Parameters))
+		for _, param := range n.Parameters {
+			e.string(param)
+		}
+		if err := e.node(n.Body); err != nil {
+			return err
+		}
+	case nil:
+		e.tag(binaryNil)
+		return nil
+	default:
+		return fmt.Errorf("MarshalNode: unsupported node type %T", n)
+	}
+	e.pos(n.Pos())
+	e.pos(n.End())
+	return nil
+}
+
+func (e *encoder) exprs(exprs []Expression) error {
+	e.int(len(exprs))
+	for _, expr := range exprs {
+		if err := e.node(expr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *encoder) tag(t byte) {
+	e.buf = append(e.buf, t)
+}
+
+func (e *encoder) int(n int) {
+	e.buf = binary.AppendVarint(e.buf, int64(n))
+}
+
+func (e *encoder) string(s string) {
+	e.int(len(s))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encoder) pos(p Position) {
+	e.int(p.line)
+	e.int(p.column)
+	e.int(p.offset)
+}
+
+type decoder struct {
+	buf []byte
+}
+
+// expr decodes one expression, which is nil if it was encoded as nil.
+func (d *decoder) expr() (Expression, error) {
+	tag, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	var expr Expression
+	switch tag {
+	case binaryNil:
+		return nil, nil
+	case binaryBinary:
+		e := &BinaryExpression{}
+		op, err := d.int()
+		if err != nil {
+			return nil, err
+		}
+		e.Op = Token(op)
+		if e.Left, err = d.expr(); err != nil {
+			return nil, err
+		}
+		if e.Right, err = d.expr(); err != nil {
+			return nil, err
+		}
+		if e.Position, e.EndPosition, err = d.span(); err != nil {
+			return nil, err
+		}
+		expr = e
+	case binaryNary:
+		e := &NaryExpression{}
+		op, err := d.int()
+		if err != nil {
+			return nil, err
+		}
+		e.Op = Token(op)
+		if e.Operands, err = d.exprs(); err != nil {
+			return nil, err
+		}
+		if e.Position, e.EndPosition, err = d.span(); err != nil {
+			return nil, err
+		}
+		expr = e
+	case binaryUnary:
+		e := &UnaryExpression{}
+		op, err := d.int()
+		if err != nil {
+			return nil, err
+		}
+		e.Op = Token(op)
+		postfix, err := d.int()
+		if err != nil {
+			return nil, err
+		}
+		e.Postfix = postfix != 0
+		if e.Operand, err = d.expr(); err != nil {
+			return nil, err
+		}
+		if e.Position, e.EndPosition, err = d.span(); err != nil {
+			return nil, err
+		}
+		expr = e
+	case binaryInteger:
+		e := &IntegerLiteral{}
+		if e.Value, err = d.int(); err != nil {
+			return nil, err
+		}
+		if e.Literal, err = d.string(); err != nil {
+			return nil, err
+		}
+		if e.Position, e.EndPosition, err = d.span(); err != nil {
+			return nil, err
+		}
+		expr = e
+	case binaryFloat:
+		e := &FloatLiteral{}
+		if len(d.buf) < 8 {
+			return nil, errTruncated
+		}
+		e.Value = math.Float64frombits(binary.LittleEndian.Uint64(d.buf))
+		d.buf = d.buf[8:]
+		if e.Literal, err = d.string(); err != nil {
+			return nil, err
+		}
+		if e.Position, e.EndPosition, err = d.span(); err != nil {
+			return nil, err
+		}
+		expr = e
+	case binaryString:
+		e := &StringLiteral{}
+		if e.Value, err = d.string(); err != nil {
+			return nil, err
+		}
+		if e.Position, e.EndPosition, err = d.span(); err != nil {
+			return nil, err
+		}
+		expr = e
+	case binaryIdentifier:
+		e := &Identifier{}
+		if e.Name, err = d.string(); err != nil {
+			return nil, err
+		}
+		if e.Position, e.EndPosition, err = d.span(); err != nil {
+			return nil, err
+		}
+		expr = e
+	case binaryCast:
+		e := &CastExpression{}
+		if e.Type, err = d.string(); err != nil {
+			return nil, err
+		}
+		if e.Operand, err = d.expr(); err != nil {
+			return nil, err
+		}
+		if e.Position, e.EndPosition, err = d.span(); err != nil {
+			return nil, err
+		}
+		expr = e
+	case binaryRange:
+		e := &RangeExpression{}
+		if e.From, err = d.expr(); err != nil {
+			return nil, err
+		}
+		if e.To, err = d.expr(); err != nil {
+			return nil, err
+		}
+		if e.Position, e.EndPosition, err = d.span(); err != nil {
+			return nil, err
+		}
+		expr = e
+	case binaryList:
+		e := &ExpressionList{}
+		if e.Elements, err = d.exprs(); err != nil {
+			return nil, err
+		}
+		if e.Position, e.EndPosition, err = d.span(); err != nil {
+			return nil, err
+		}
+		expr = e
+	case binaryCall:
+		e := &CallExpression{}
+		if e.Function, err = d.string(); err != nil {
+			return nil, err
+		}
+		if e.Arguments, err = d.exprs(); err != nil {
+			return nil, err
+		}
+		if e.Position, e.EndPosition, err = d.span(); err != nil {
+			return nil, err
+		}
+		expr = e
+	case binaryAssignment:
+		e := &Assignment{}
+		if e.Name, err = d.string(); err != nil {
+			return nil, err
+		}
+		if e.Value, err = d.expr(); err != nil {
+			return nil, err
+		}
+		if e.Position, e.EndPosition, err = d.span(); err != nil {
+			return nil, err
+		}
+		expr = e
+	case binaryFunction:
+		e := &FunctionDefinition{}
+		if e.Name, err = d.string(); err != nil {
+			return nil, err
+		}
+		n, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		e.Parameters = make([]string, n)
+		for i := range e.Parameters {
+			if e.Parameters[i], err = d.string(); err != nil {
+				return nil, err
+			}
+		}
+		if e.Body, err = d.expr(); err != nil {
+			return nil, err
+		}
+		if e.Position, e.EndPosition, err = d.span(); err != nil {
+			return nil, err
+		}
+		expr = e
+	default:
+		return nil, fmt.Errorf("invalid node tag %d", tag)
+	}
+	return expr, nil
+}
+
+func (d *decoder) exprs() ([]Expression, error) {
+	n, err := d.length()
+	if err != nil {
+		return nil, err
+	}
+	exprs := make([]Expression, n)
+	for i := range exprs {
+		if exprs[i], err = d.expr(); err != nil {
+			return nil, err
+		}
+	}
+	return exprs, nil
+}
+
+func (d *decoder) byte() (byte, error) {
+	if len(d.buf) == 0 {
+		return 0, errTruncated
+	}
+	b := d.buf[0]
+	d.buf = d.buf[1:]
+	return b, nil
+}
+
+func (d *decoder) int() (int, error) {
+	n, size := binary.Varint(d.buf)
+	if size <= 0 {
+		return 0, errTruncated
+	}
+	d.buf = d.buf[size:]
+	return int(n), nil
+}
+
+// length decodes a count of items that follow, each of which takes at least
+// one byte, so that corrupt data cannot make it allocate without bound.
+func (d *decoder) length() (int, error) {
+	n, err := d.int()
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > len(d.buf) {
+		return 0, errTruncated
+	}
+	return n, nil
+}
+
+func (d *decoder) string() (string, error) {
+	n, err := d.length()
+	if err != nil {
+		return "", err
+	}
+	s := string(d.buf[:n])
+	d.buf = d.buf[n:]
+	return s, nil
+}
+
+func (d *decoder) span() (start, end Position, err error) {
+	if start, err = d.pos(); err != nil {
+		return
+	}
+	end, err = d.pos()
+	return
+}
+
+func (d *decoder) pos() (Position, error) {
+	var p Position
+	var err error
+	if p.line, err = d.int(); err != nil {
+		return p, err
+	}
+	if p.column, err = d.int(); err != nil {
+		return p, err
+	}
+	p.offset, err = d.int()
+	return p, err
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding"
+	"errors"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	inputs := []string{
+		"1+2*3",
+		"-x ^ 2.5 / f(y, 1)",
+		`"a" + "b"`,
+		"(1, 2)..10",
+		"3 as float < 2",
+	}
+	for _, input := range inputs {
+		expr := mustParse(t, input)
+		data, err := expr.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary(%q): %v", input, err)
+		}
+		got, err := UnmarshalNode(data)
+		if err != nil {
+			t.Fatalf("UnmarshalNode(%q): %v", input, err)
+		}
+		if e, ok := got.(Expression); !ok || !Equal(e, expr) {
+			t.Errorf("round trip of %q = %s", input, got)
+		}
+		if got.Pos() != expr.Pos() || got.End() != expr.End() {
+			t.Errorf("round trip of %q spans %s-%s, want %s-%s", input, got.Pos(), got.End(), expr.Pos(), expr.End())
+		}
+	}
+}
+
+func TestBinaryProgramRoundTrip(t *testing.T) {
+	program, err := ParseProgram("def f(a) = a * 2; x = f(3); x + 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := MarshalNode(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := UnmarshalNode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != program.String() {
+		t.Errorf("round trip = %s, want %s", got, program)
+	}
+}
+
+func TestUnmarshalNodeErrors(t *testing.T) {
+	data, err := MarshalNode(mustParse(t, "1+2*3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UnmarshalNode(data[:len(data)-1]); !errors.Is(err, errTruncated) {
+		t.Errorf("UnmarshalNode of truncated data error = %v, want errTruncated", err)
+	}
+	if _, err := UnmarshalNode(append(data, 0)); err == nil {
+		t.Error("UnmarshalNode accepted trailing data")
+	}
+	if _, err := UnmarshalNode([]byte{200}); err == nil {
+		t.Error("UnmarshalNode accepted an invalid tag")
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// builtin is a function available without a def. The evaluator checks the
+// argument count against min and max before calling fn, so fn may index its
+// arguments freely; max < 0 means there is no upper bound.
+type builtin struct {
+	min, max int
+	fn       func(args []Value) (Value, error)
+}
+
+// builtins are the functions available without a def. A user definition of
+// the same name takes precedence.
+var builtins = map[string]builtin{
+	"abs":     {1, 1, builtinAbs},
+	"sum":     {0, -1, builtinSum},
+	"product": {0, -1, builtinProduct},
+	"avg":     {0, -1, builtinAvg},
+	"isqrt":   {1, 1, builtinIsqrt},
+	"min":     {1, -1, builtinMin},
+	"max":     {1, -1, builtinMax},
+	"clamp":   {3, 3, builtinClamp},
+}
+
+func (ev *evaluator) callBuiltin(c *CallExpression, b builtin) (Value, error) {
+	if n := len(c.Arguments); n < b.min || (b.max >= 0 && n > b.max) {
+		return Value{}, fmt.Errorf("%s expects %s, got %d", c.Function, arity(b.min, b.max), n)
+	}
+	args := make([]Value, len(c.Arguments))
+	for i, arg := range c.Arguments {
+		v, err := ev.eval(arg)
+		if err != nil {
+			return Value{}, err
+		}
+		args[i] = v
+	}
+	return b.fn(args)
+}
+
+// callIf evaluates if(cond, then, else). Unlike other builtins it takes its
+// arguments unevaluated, so that only the branch cond selects is evaluated.
+func (ev *evaluator) callIf(c *CallExpression) (Value, error) {
+	if len(c.Arguments) != 3 {
+		return Value{}, fmt.Errorf("if expects %s, got %d", arity(3, 3), len(c.Arguments))
+	}
+	cond, err := ev.eval(c.Arguments[0])
+	if err != nil {
+		return Value{}, err
+	}
+	var taken bool
+	switch cond.Kind {
+	case BoolValue:
+		taken = cond.Bool
+	case IntValue:
+		taken = cond.Int != 0
+	case BigValue:
+		taken = cond.Big.Sign() != 0
+	case FloatValue:
+		taken = cond.Float != 0
+	default:
+		return Value{}, fmt.Errorf("if condition must be a bool or number, got %s %s", cond.Kind, cond.Quote())
+	}
+	if taken {
+		return ev.eval(c.Arguments[1])
+	}
+	return ev.eval(c.Arguments[2])
+}
+
+// arity describes the accepted argument counts, as in "1 argument" or "1 to 3
+// arguments".
+func arity(min, max int) string {
+	switch {
+	case min == max:
+		return fmt.Sprintf("%d %s", min, plural(min, "argument"))
+	case max < 0:
+		return fmt.Sprintf("at least %d %s", min, plural(min, "argument"))
+	}
+	return fmt.Sprintf("%d to %d arguments", min, max)
+}
+
+// builtinAbs returns the magnitude of an integer or float. abs of the most
+// negative int does not fit in an int, so it is promoted to a big integer.
+func builtinAbs(args []Value) (Value, error) {
+	switch v := args[0]; v.Kind {
+	case IntValue:
+		if v.Int == math.MinInt {
+			return Value{Kind: BigValue, Big: new(big.Int).Abs(v.bigInt())}, nil
+		}
+		if v.Int < 0 {
+			return Value{Kind: IntValue, Int: -v.Int}, nil
+		}
+		return v, nil
+	case BigValue:
+		return Value{Kind: BigValue, Big: new(big.Int).Abs(v.Big)}, nil
+	case FloatValue:
+		return Value{Kind: FloatValue, Float: math.Abs(v.Float)}, nil
+	default:
+		return Value{}, fmt.Errorf("cannot take abs of %s %s", v.Kind, v.Quote())
+	}
+}
+
+func builtinSum(args []Value) (Value, error) {
+	return fold(ADD, Value{Kind: IntValue}, elements(args))
+}
+
+func builtinProduct(args []Value) (Value, error) {
+	return fold(MUL, Value{Kind: IntValue, Int: 1}, elements(args))
+}
+
+// builtinAvg returns the mean as a float, so avg(1, 2) is 1.5.
+func builtinAvg(args []Value) (Value, error) {
+	values := elements(args)
+	if len(values) == 0 {
+		return Value{}, fmt.Errorf("avg of an empty sequence")
+	}
+	total, err := fold(ADD, Value{Kind: IntValue}, values)
+	if err != nil {
+		return Value{}, err
+	}
+	return Value{Kind: FloatValue, Float: total.float() / float64(len(values))}, nil
+}
+
+// elements returns the values an aggregate folds over: the elements of its
+// argument if it was given a single sequence, as in sum(1..10), or else the
+// arguments themselves, as in sum(1, 2, 3).
+func elements(args []Value) []Value {
+	if len(args) == 1 && args[0].Kind == SeqValue {
+		return args[0].Seq
+	}
+	return args
+}
+
+func fold(op Token, acc Value, values []Value) (Value, error) {
+	for _, v := range values {
+		var err error
+		if acc, err = applyValueOp(op, acc, v); err != nil {
+			return Value{}, err
+		}
+	}
+	return acc, nil
+}
+
+// builtinIsqrt returns the floor of the square root of a non-negative
+// integer, computed without going through floating point.
+func builtinIsqrt(args []Value) (Value, error) {
+	v := args[0]
+	if v.Kind != IntValue && v.Kind != BigValue {
+		return Value{}, fmt.Errorf("isqrt expects an integer, got %s %s", v.Kind, v.Quote())
+	}
+	n := v.bigInt()
+	if n.Sign() < 0 {
+		return Value{}, fmt.Errorf("isqrt of negative number %s", v)
+	}
+	root := new(big.Int).Sqrt(n)
+	if v.Kind == IntValue {
+		return Value{Kind: IntValue, Int: int(root.Int64())}, nil
+	}
+	return Value{Kind: BigValue, Big: root}, nil
+}
+
+func builtinMin(args []Value) (Value, error) {
+	return extreme("min", LT, args)
+}
+
+func builtinMax(args []Value) (Value, error) {
+	return extreme("max", GT, args)
+}
+
+// extreme returns the first argument that no other argument beats under op.
+func extreme(name string, op Token, args []Value) (Value, error) {
+	best := args[0]
+	for _, v := range args {
+		if !v.isNumber() {
+			return Value{}, fmt.Errorf("%s expects numbers, got %s %s", name, v.Kind, v.Quote())
+		}
+		beats, err := compare(op, v, best)
+		if err != nil {
+			return Value{}, err
+		}
+		if beats.Bool {
+			best = v
+		}
+	}
+	return best, nil
+}
+
+// builtinClamp limits x to the range lo to hi.
+func builtinClamp(args []Value) (Value, error) {
+	x, lo, hi := args[0], args[1], args[2]
+	for _, v := range args {
+		if !v.isNumber() {
+			return Value{}, fmt.Errorf("clamp expects numbers, got %s %s", v.Kind, v.Quote())
+		}
+	}
+	if inverted, _ := compare(GT, lo, hi); inverted.Bool {
+		return Value{}, fmt.Errorf("clamp bounds %s and %s are inverted", lo, hi)
+	}
+	if below, _ := compare(LT, x, lo); below.Bool {
+		return lo, nil
+	}
+	if above, _ := compare(GT, x, hi); above.Bool {
+		return hi, nil
+	}
+	return x, nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAbs(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"abs(-5)", "5"},
+		{"abs(5)", "5"},
+		{"abs(-2.5)", "2.5"},
+		{"abs(2.5)", "2.5"},
+		{"abs(-inf)", "inf"},
+		{"abs(0 - 0.0)", "0.0"},
+	}
+	for _, tt := range tests {
+		v, err := EvalValue(tt.input)
+		if err != nil {
+			t.Fatalf("EvalValue(%q): %v", tt.input, err)
+		}
+		if got := v.String(); got != tt.want {
+			t.Errorf("EvalValue(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestAbsMinInt(t *testing.T) {
+	expr, err := Parse("abs(n)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := EvaluateValue(expr, map[string]int{"n": math.MinInt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Kind != BigValue || v.String() != "9223372036854775808" {
+		t.Errorf("abs(MinInt) = %s %s, want big 9223372036854775808", v.Kind, v)
+	}
+}
+
+func TestBuiltinArity(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"abs()", "abs expects 1 argument, got 0"},
+		{"abs(1, 2)", "abs expects 1 argument, got 2"},
+		{"clamp(1, 2)", "clamp expects 3 arguments, got 2"},
+		{"clamp(1, 2, 3, 4)", "clamp expects 3 arguments, got 4"},
+		{"isqrt()", "isqrt expects 1 argument, got 0"},
+		{"min()", "min expects at least 1 argument, got 0"},
+		{"if(1, 2)", "if expects 3 arguments, got 2"},
+	}
+	for _, tt := range tests {
+		if _, err := Eval(tt.input); err == nil || err.Error() != tt.want {
+			t.Errorf("Eval(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+// checkResults fails t unless each input evaluates to a value printing as
+// its expected string.
+func checkResults(t *testing.T, tests []struct{ input, want string }) {
+	t.Helper()
+	for _, tt := range tests {
+		v, err := EvalValue(tt.input)
+		if err != nil {
+			t.Errorf("EvalValue(%q): %v", tt.input, err)
+			continue
+		}
+		if got := v.String(); got != tt.want {
+			t.Errorf("EvalValue(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+// checkErrors fails t unless each input fails to evaluate with its expected
+// message.
+func checkErrors(t *testing.T, tests []struct{ input, want string }) {
+	t.Helper()
+	for _, tt := range tests {
+		if _, err := EvalValue(tt.input); err == nil || err.Error() != tt.want {
+			t.Errorf("EvalValue(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestIsqrt(t *testing.T) {
+	checkResults(t, []struct{ input, want string }{
+		{"isqrt(16)", "4"},
+		{"isqrt(15)", "3"},
+		{"isqrt(0)", "0"},
+		{"isqrt(1)", "1"},
+		{"isqrt(9223372036854775807)", "3037000499"},
+	})
+	checkErrors(t, []struct{ input, want string }{
+		{"isqrt(-1)", "isqrt of negative number -1"},
+	})
+}
+
+func TestMinMaxClamp(t *testing.T) {
+	checkResults(t, []struct{ input, want string }{
+		{"max(3, 7, 2)", "7"},
+		{"min(3, 7, 2)", "2"},
+		{"max(5)", "5"},
+		{"min(2.5, 3)", "2.5"},
+		{"max(1, 1.5)", "1.5"},
+		{"clamp(10, 0, 5)", "5"},
+		{"clamp(-3, 0, 5)", "0"},
+		{"clamp(3, 0, 5)", "3"},
+	})
+	checkErrors(t, []struct{ input, want string }{
+		{"max()", "max expects at least 1 argument, got 0"},
+		{"min()", "min expects at least 1 argument, got 0"},
+	})
+}
+
+func TestIf(t *testing.T) {
+	checkResults(t, []struct{ input, want string }{
+		{"if(1, 1, 1/0)", "1"},
+		{"if(0, 1/0, 2)", "2"},
+		{"if(3 > 2, 10, 20)", "10"},
+		{"if(3 < 2, 10, 20)", "20"},
+	})
+	checkErrors(t, []struct{ input, want string }{
+		{"if(1, 1/0, 2)", "division by zero"},
+		{"if(1, 2)", "if expects 3 arguments, got 2"},
+	})
+}
+
+func TestAggregates(t *testing.T) {
+	checkResults(t, []struct{ input, want string }{
+		{"sum(1..10)", "55"},
+		{"sum(1, 2, 3)", "6"},
+		{"sum()", "0"},
+		{"product(1..5)", "120"},
+		{"product(2, 3, 4)", "24"},
+		{"product()", "1"},
+		{"avg(1..4)", "2.5"},
+		{"avg(1, 2)", "1.5"},
+		{"sum(0.5, 1)", "1.5"},
+	})
+	checkErrors(t, []struct{ input, want string }{
+		{"avg()", "avg of an empty sequence"},
+	})
+}
@@ -0,0 +1,126 @@
+package main
+
+// CachedEvaluator evaluates one expression again and again in environments
+// that differ only in a few variables, as when plotting y over a range of x.
+// Subtrees that mention none of the varying variables are computed once and
+// reused on later calls for as long as the variables they do mention keep
+// their values, so in x*x + 3*7 - 2 only x*x and the sums are recomputed.
+//
+// Like EvaluateMemo, it combines the operands of binary expressions itself
+// and evaluates every other node as evaluateExpression would. A
+// CachedEvaluator is not safe for concurrent use.
+type CachedEvaluator struct {
+	expr  Expression
+	vary  map[string]bool
+	cache map[Expression]*cachedResult
+}
+
+// cachedResult is the value of an invariant subtree, valid while the free
+// variables in names have the values in env.
+type cachedResult struct {
+	names []string
+	env   []int
+	value Value
+	ok    bool
+}
+
+// NewCachedEvaluator prepares expr for evaluation in environments where only
+// the variables named in vary change between calls to Eval.
+func NewCachedEvaluator(expr Expression, vary ...string) *CachedEvaluator {
+	c := &CachedEvaluator{expr: expr, vary: make(map[string]bool, len(vary)), cache: make(map[Expression]*cachedResult)}
+	for _, name := range vary {
+		c.vary[name] = true
+	}
+	if !c.plan(expr) {
+		c.remember(expr)
+	}
+	return c
+}
+
+// plan reports whether expr depends on a varying variable, and arranges for
+// the invariant operands of any dependent operator to be cached.
+func (c *CachedEvaluator) plan(expr Expression) bool {
+	be, ok := expr.(*BinaryExpression)
+	if !ok {
+		for _, name := range FreeVars(expr) {
+			if c.vary[name] {
+				return true
+			}
+		}
+		return false
+	}
+
+	left, right := c.plan(be.Left), c.plan(be.Right)
+	if !left && !right {
+		return false
+	}
+	if !left {
+		c.remember(be.Left)
+	}
+	if !right {
+		c.remember(be.Right)
+	}
+	return true
+}
+
+func (c *CachedEvaluator) remember(expr Expression) {
+	if _, ok := expr.(*IntegerLiteral); ok {
+		return
+	}
+	names := FreeVars(expr)
+	c.cache[expr] = &cachedResult{names: names, env: make([]int, len(names))}
+}
+
+// Eval evaluates the expression with variables resolved in env.
+func (c *CachedEvaluator) Eval(env map[string]int) (int, error) {
+	v, err := c.eval(newEvaluator(env), c.expr, env)
+	if err != nil {
+		return 0, err
+	}
+	return v.integer()
+}
+
+func (c *CachedEvaluator) eval(ev *evaluator, expr Expression, env map[string]int) (Value, error) {
+	r, cached := c.cache[expr]
+	if cached && r.valid(env) {
+		return r.value, nil
+	}
+
+	var v Value
+	var err error
+	if be, ok := expr.(*BinaryExpression); ok {
+		var left, right Value
+		if left, err = c.eval(ev, be.Left, env); err != nil {
+			return Value{}, err
+		}
+		if right, err = c.eval(ev, be.Right, env); err != nil {
+			return Value{}, err
+		}
+		v, err = ev.binary(be, left, right)
+	} else {
+		v, err = ev.eval(expr)
+	}
+	if err != nil {
+		return Value{}, err
+	}
+
+	if cached {
+		for i, name := range r.names {
+			r.env[i] = env[name]
+		}
+		r.value, r.ok = v, true
+	}
+	return v, nil
+}
+
+func (r *cachedResult) valid(env map[string]int) bool {
+	if !r.ok {
+		return false
+	}
+	for i, name := range r.names {
+		if v, ok := env[name]; !ok || v != r.env[i] {
+			return false
+		}
+	}
+	return true
+}
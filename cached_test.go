@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestCachedEvaluatorComputesInvariantsOnce(t *testing.T) {
+	calls := countingBuiltin(t, "seven")
+	c := NewCachedEvaluator(mustParse(t, "x*x + 3*seven() - 2"), "x")
+	for x := 0; x < 10; x++ {
+		got, err := c.Eval(map[string]int{"x": x})
+		if want := x*x + 3*3 - 2; err != nil || got != want {
+			t.Errorf("Eval(x=%d) = %d, %v, want %d", x, got, err, want)
+		}
+	}
+	if *calls != 1 {
+		t.Errorf("the invariant subtree was evaluated %d times, want 1", *calls)
+	}
+}
+
+func TestCachedEvaluatorInvalidates(t *testing.T) {
+	c := NewCachedEvaluator(mustParse(t, "x + y * 2"), "x")
+	for _, tt := range []struct {
+		x, y, want int
+	}{
+		{1, 1, 3},
+		{2, 1, 4},
+		{2, 5, 12},
+	} {
+		if got, err := c.Eval(map[string]int{"x": tt.x, "y": tt.y}); err != nil || got != tt.want {
+			t.Errorf("Eval(x=%d, y=%d) = %d, %v, want %d", tt.x, tt.y, got, err, tt.want)
+		}
+	}
+	if _, err := c.Eval(map[string]int{"x": 1}); err == nil {
+		t.Error("Eval without y succeeded from a stale cache")
+	}
+}
+
+func BenchmarkCachedEvaluator(b *testing.B) {
+	calls := countingBuiltin(b, "seven")
+	c := NewCachedEvaluator(mustParseB(b, "x*x + 3*seven() - 2"), "x")
+	env := map[string]int{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		env["x"] = i
+		if _, err := c.Eval(env); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	if *calls != 1 {
+		b.Errorf("the invariant subtree was evaluated %d times, want 1", *calls)
+	}
+}
+
+func BenchmarkUncachedEvaluator(b *testing.B) {
+	expr := mustParseB(b, "x*x + 3*7 - 2")
+	env := map[string]int{}
+	for i := 0; i < b.N; i++ {
+		env["x"] = i
+		if _, err := evaluateExpression(expr, env); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+// Clone returns a deep copy of n, positions included, so that the copy can be
+// modified without affecting n.
+func Clone(n Node) Node {
+	switch e := n.(type) {
+	case *Program:
+		return &Program{Statements: cloneAll(e.Statements)}
+	case Expression:
+		return cloneExpr(e)
+	default:
+		return n
+	}
+}
+
+func cloneExpr(expr Expression) Expression {
+	switch e := expr.(type) {
+	case *BinaryExpression:
+		copied := *e
+		copied.Left = cloneExpr(e.Left)
+		copied.Right = cloneExpr(e.Right)
+		return &copied
+	case *NaryExpression:
+		copied := *e
+		copied.Operands = cloneAll(e.Operands)
+		return &copied
+	case *UnaryExpression:
+		copied := *e
+		copied.Operand = cloneExpr(e.Operand)
+		return &copied
+	case *CastExpression:
+		copied := *e
+		copied.Operand = cloneExpr(e.Operand)
+		return &copied
+	case *RangeExpression:
+		copied := *e
+		copied.From = cloneExpr(e.From)
+		copied.To = cloneExpr(e.To)
+		return &copied
+	case *ExpressionList:
+		copied := *e
+		copied.Elements = cloneAll(e.Elements)
+		return &copied
+	case *CallExpression:
+		copied := *e
+		copied.Arguments = cloneAll(e.Arguments)
+		return &copied
+	case *Assignment:
+		copied := *e
+		copied.Value = cloneExpr(e.Value)
+		return &copied
+	case *FunctionDefinition:
+		copied := *e
+		copied.Parameters = append([]string(nil), e.Parameters...)
+		copied.Body = cloneExpr(e.Body)
+		return &copied
+	case *Identifier:
+		copied := *e
+		return &copied
+	case *IntegerLiteral:
+		copied := *e
+		return &copied
+	case *FloatLiteral:
+		copied := *e
+		return &copied
+	case *StringLiteral:
+		copied := *e
+		return &copied
+	default:
+		return expr
+	}
+}
+
+func cloneAll(exprs []Expression) []Expression {
+	if exprs == nil {
+		return nil
+	}
+	out := make([]Expression, len(exprs))
+	for i, expr := range exprs {
+		out[i] = cloneExpr(expr)
+	}
+	return out
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	original := mustParse(t, "(1 + x) * f(2, -y)")
+	clone := Clone(original).(*BinaryExpression)
+	if !reflect.DeepEqual(clone, original) {
+		t.Fatalf("Clone = %#v, want a copy of %#v, positions included", clone, original)
+	}
+
+	clone.Op = SUB
+	clone.Left.(*BinaryExpression).Left.(*IntegerLiteral).Value = 100
+	clone.Right.(*CallExpression).Arguments[0] = &Identifier{Name: "z"}
+
+	if want := mustParse(t, "(1 + x) * f(2, -y)"); !Equal(original, want) {
+		t.Errorf("modifying the clone changed the original to %s", original)
+	}
+	if got, want := clone.String(), "((100 + x) - f(z, (-y)))"; got != want {
+		t.Errorf("modified clone = %s, want %s", got, want)
+	}
+}
+
+func TestCloneProgram(t *testing.T) {
+	program, err := ParseProgram("x = 1; def f(a) = a + x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := Clone(program).(*Program)
+	clone.Statements[1].(*FunctionDefinition).Parameters[0] = "b"
+	if got := program.String(); got != "x = 1; def f(a) = (a + x)" {
+		t.Errorf("modifying the clone changed the original to %s", got)
+	}
+}
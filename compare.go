@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// compare evaluates a comparison operator. Numbers of any kind compare by
+// value, strings lexically, and bools only for equality. Following IEEE 754,
+// every comparison involving NaN is false except !=, which is true.
+func compare(op Token, left, right Value) (Value, error) {
+	var c int
+	switch {
+	case left.Kind == IntValue && right.Kind == IntValue:
+		c = cmpInt(left.Int, right.Int)
+	case left.isNumber() && right.isNumber() && (left.Kind == FloatValue || right.Kind == FloatValue):
+		l, r := left.float(), right.float()
+		if math.IsNaN(l) || math.IsNaN(r) {
+			return Value{Kind: BoolValue, Bool: op == NEQ}, nil
+		}
+		c = cmpFloat(l, r)
+	case left.isNumber() && right.isNumber():
+		c = left.bigInt().Cmp(right.bigInt())
+	case left.Kind == StringValue && right.Kind == StringValue:
+		c = strings.Compare(left.Str, right.Str)
+	case left.Kind == BoolValue && right.Kind == BoolValue:
+		if op != EQ && op != NEQ {
+			return Value{}, fmt.Errorf("operator %s not defined on bools", TokenName(op))
+		}
+		if left.Bool != right.Bool {
+			c = 1
+		}
+	default:
+		return Value{}, fmt.Errorf("mismatched types %s and %s for %s", left.Kind, right.Kind, TokenName(op))
+	}
+
+	var result bool
+	switch op {
+	case EQ:
+		result = c == 0
+	case NEQ:
+		result = c != 0
+	case LT:
+		result = c < 0
+	case LE:
+		result = c <= 0
+	case GT:
+		result = c > 0
+	case GE:
+		result = c >= 0
+	}
+	return Value{Kind: BoolValue, Bool: result}, nil
+}
+
+func (v Value) isNumber() bool {
+	return v.Kind == IntValue || v.Kind == BigValue || v.Kind == FloatValue
+}
+
+// isInteger reports whether v is an int or a big integer.
+func (v Value) isInteger() bool {
+	return v.Kind == IntValue || v.Kind == BigValue
+}
+
+// isZero reports whether v is a number equal to zero.
+func (v Value) isZero() bool {
+	switch v.Kind {
+	case IntValue:
+		return v.Int == 0
+	case BigValue:
+		return v.Big.Sign() == 0
+	case FloatValue:
+		return v.Float == 0
+	}
+	return false
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
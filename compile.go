@@ -0,0 +1,63 @@
+package main
+
+// Compile turns expr into a function that evaluates it like
+// evaluateExpression, for callers that evaluate one formula against many
+// environments. The tree is walked once, here; integer arithmetic on literals
+// and variables becomes nested closures, and any other subtree is handed to
+// evaluateExpression when the function runs. Arithmetic is only compiled when
+// every operand is an integer, so that a float or bool partway through fails
+// with the same error as it does in evaluateExpression.
+func Compile(expr Expression) func(env map[string]int) (int, error) {
+	return compile(expr)
+}
+
+func compile(expr Expression) func(env map[string]int) (int, error) {
+	switch e := expr.(type) {
+	case *BinaryExpression:
+		if e.Op != ADD && e.Op != SUB && e.Op != MUL && e.Op != DIV || !isIntegral(e) {
+			break
+		}
+		left, right, op := compile(e.Left), compile(e.Right), e.Op
+		return func(env map[string]int) (int, error) {
+			l, err := left(env)
+			if err != nil {
+				return 0, err
+			}
+			r, err := right(env)
+			if err != nil {
+				return 0, err
+			}
+			return applyOp(op, l, r)
+		}
+
+	case *UnaryExpression:
+		if !isIntegral(e) {
+			break
+		}
+		operand := compile(e.Operand)
+		return func(env map[string]int) (int, error) {
+			v, err := operand(env)
+			return -v, err
+		}
+
+	case *IntegerLiteral:
+		v := e.Value
+		return func(map[string]int) (int, error) {
+			return v, nil
+		}
+
+	case *Identifier:
+		name := e.Name
+		return func(env map[string]int) (int, error) {
+			if v, ok := env[name]; ok {
+				return v, nil
+			}
+			// Constants, OS variables and the error for an undefined name.
+			return evaluateExpression(expr, env)
+		}
+	}
+
+	return func(env map[string]int) (int, error) {
+		return evaluateExpression(expr, env)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestCompileAgrees(t *testing.T) {
+	compiled := func(expr Expression, env map[string]int) (int, error) {
+		return Compile(expr)(env)
+	}
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 500; i++ {
+		expr := randomExpression(r, 5)
+		fn := Compile(expr)
+		for x := -3; x <= 3; x++ {
+			env := map[string]int{"x": x, "y": 7 - x}
+			checkAgreement(t, "Compile", func(Expression, map[string]int) (int, error) { return fn(env) }, expr, env)
+		}
+	}
+	for _, input := range []string{"2 ^ -1 * 0", "(1 < 2) + 1", "1.5 + 1", "x / 0", "w * 2", "inf - inf", "-(2.5) + 1"} {
+		checkAgreement(t, "Compile", compiled, mustParse(t, input), map[string]int{"x": 1})
+	}
+}
+
+// benchmarkFormula is a formula evaluated against many values of x, as when
+// plotting it.
+const benchmarkFormula = "3 * x * x - 2 * x + 7 - x / 3"
+
+func BenchmarkCompile(b *testing.B) {
+	fn := Compile(mustParseB(b, benchmarkFormula))
+	env := map[string]int{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		env["x"] = i
+		if _, err := fn(env); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInterpret(b *testing.B) {
+	expr := mustParseB(b, benchmarkFormula)
+	env := map[string]int{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		env["x"] = i
+		if _, err := evaluateExpression(expr, env); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func mustParseB(b *testing.B, input string) Expression {
+	b.Helper()
+	expr, err := Parse(input)
+	if err != nil {
+		b.Fatalf("Parse(%q): %v", input, err)
+	}
+	return expr
+}
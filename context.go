@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EvaluateContext evaluates expr like evaluateExpression, checking ctx before
+// each node, and now and then while building a long range, and aborting with
+// ctx.Err() once it is cancelled or expires.
+func EvaluateContext(ctx context.Context, expr Expression, env map[string]int) (int, error) {
+	ev := newEvaluator(env)
+	ev.ctx = ctx
+	v, err := ev.eval(expr)
+	if err != nil {
+		return 0, err
+	}
+	return v.integer()
+}
+
+// EvalTimeout parses and evaluates input, giving up once d has elapsed. The
+// deadline covers parsing as well as evaluation. A timeout is reported as an
+// error wrapping context.DeadlineExceeded.
+func EvalTimeout(input string, d time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	l := NewLexer(bufio.NewReader(strings.NewReader(input)))
+	expr, err := ParseTokens(&contextTokenizer{ctx: ctx, tokens: l})
+	switch {
+	case err != nil && ctx.Err() != nil:
+		// The deadline cut the input short, which explains the parse error.
+		return 0, fmt.Errorf("parsing timed out after %s: %w", d, ctx.Err())
+	case l.Err() != nil:
+		return 0, l.Err()
+	case err != nil:
+		return 0, err
+	}
+
+	v, err := EvaluateContext(ctx, expr, nil)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return 0, fmt.Errorf("evaluation timed out after %s: %w", d, err)
+	}
+	return v, err
+}
+
+// contextTokenizer passes on the tokens of another Tokenizer until ctx is
+// done, and then ends the input, so that a parse stops part way through.
+type contextTokenizer struct {
+	ctx    context.Context
+	tokens Tokenizer
+	end    Position
+}
+
+func (c *contextTokenizer) Next() TokenInfo {
+	if c.ctx.Err() != nil {
+		return TokenInfo{Token: EOF, Pos: c.end, End: c.end}
+	}
+	t := c.tokens.Next()
+	c.end = t.End
+	return t
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvaluateContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := EvaluateContext(ctx, mustParse(t, "1 + 2 * 3"), nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("EvaluateContext error = %v, want context.Canceled", err)
+	}
+}
+
+func TestEvaluateContext(t *testing.T) {
+	v, err := EvaluateContext(context.Background(), mustParse(t, "x + 2 * 3"), map[string]int{"x": 1})
+	if err != nil || v != 7 {
+		t.Errorf("EvaluateContext = %d, %v, want 7", v, err)
+	}
+}
+
+// cancellingBuiltin registers a builtin, name(), that calls cancel and
+// returns 1, for the duration of t.
+func cancellingBuiltin(t *testing.T, name string, cancel func()) {
+	t.Helper()
+	builtins[name] = builtin{0, 0, func([]Value) (Value, error) {
+		cancel()
+		return Value{Kind: IntValue, Int: 1}, nil
+	}}
+	t.Cleanup(func() { delete(builtins, name) })
+}
+
+func TestEvaluateContextBelowNonBinaryNodes(t *testing.T) {
+	inputs := []string{
+		"-(stop() + 1)",
+		"abs(stop() + 1)",
+		"|stop() - 5|",
+		"(stop() + 1) as int",
+		"sum((stop(), 2))",
+		"sum(1..(stop() + 3))",
+		"if(stop(), 1, 2)",
+	}
+	for _, input := range inputs {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancellingBuiltin(t, "stop", cancel)
+		_, err := EvaluateContext(ctx, mustParse(t, input), nil)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("EvaluateContext(%q) error = %v, want context.Canceled", input, err)
+		}
+	}
+}
+
+// sleepingBuiltin registers a builtin, name(), that sleeps for d and returns
+// 1, for the duration of t.
+func sleepingBuiltin(t *testing.T, name string, d time.Duration) {
+	t.Helper()
+	builtins[name] = builtin{0, 0, func([]Value) (Value, error) {
+		time.Sleep(d)
+		return Value{Kind: IntValue, Int: 1}, nil
+	}}
+	t.Cleanup(func() { delete(builtins, name) })
+}
+
+func TestEvalTimeout(t *testing.T) {
+	sleepingBuiltin(t, "nap", 50*time.Millisecond)
+	for _, input := range []string{"abs(-(nap() + 1))", "-(nap() * 2)", "(nap(), 2 + 3)"} {
+		_, err := EvalTimeout(input, 20*time.Millisecond)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("EvalTimeout(%q) error = %v, want context.DeadlineExceeded", input, err)
+			continue
+		}
+		if want := "evaluation timed out after 20ms"; !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("EvalTimeout(%q) error = %q, want it to start with %q", input, err, want)
+		}
+	}
+
+	if v, err := EvalTimeout("1 + 2", time.Minute); err != nil || v != 3 {
+		t.Errorf("EvalTimeout(1 + 2) = %d, %v, want 3", v, err)
+	}
+	if _, err := EvalTimeout("1 +", time.Minute); err == nil || errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("EvalTimeout(1 +) error = %v, want a syntax error", err)
+	}
+}
+
+func TestEvalTimeoutWhileParsing(t *testing.T) {
+	input := "abs(" + strings.Repeat("1 + ", 100000) + "1)"
+	_, err := EvalTimeout(input, time.Nanosecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("EvalTimeout error = %v, want context.DeadlineExceeded", err)
+	}
+	if want := "parsing timed out after 1ns"; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("EvalTimeout error = %q, want it to start with %q", err, want)
+	}
+}
+
+func TestContextTokenizer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tokens := &contextTokenizer{ctx: ctx, tokens: newStringLexer("1 + 2 * 3")}
+	if tok := tokens.Next(); tok.Token != INT {
+		t.Fatalf("first token = %s, want INT", TokenName(tok.Token))
+	}
+	tokens.Next()
+	cancel()
+	for i := 0; i < 2; i++ {
+		if tok := tokens.Next(); tok.Token != EOF || tok.Pos.String() != "1:4" {
+			t.Errorf("token after cancel = %s at %s, want EOF at 1:4", TokenName(tok.Token), tok.Pos)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+// CountOperations returns the number of operator nodes, binary and unary, in
+// expr, counting each call as one operation and each range as one per
+// element when its bounds are literals. It gives a cheap estimate of how
+// expensive expr is to evaluate, so that overly large formulas can be
+// rejected before evaluation.
+func CountOperations(expr Expression) int {
+	switch e := expr.(type) {
+	case *BinaryExpression:
+		return 1 + CountOperations(e.Left) + CountOperations(e.Right)
+	case *NaryExpression:
+		if len(e.Operands) == 0 {
+			return 0
+		}
+		return len(e.Operands) - 1 + countAll(e.Operands)
+	case *UnaryExpression:
+		return 1 + CountOperations(e.Operand)
+	case *CastExpression:
+		return CountOperations(e.Operand)
+	case *RangeExpression:
+		return rangeLength(e) + CountOperations(e.From) + CountOperations(e.To)
+	case *ExpressionList:
+		return countAll(e.Elements)
+	case *CallExpression:
+		return 1 + countAll(e.Arguments)
+	case *Assignment:
+		return CountOperations(e.Value)
+	case *FunctionDefinition:
+		return CountOperations(e.Body)
+	default:
+		return 0
+	}
+}
+
+// rangeLength is the number of elements r produces if both its bounds are
+// integer literals, capped at maxRangeLength, and 1 otherwise.
+func rangeLength(r *RangeExpression) int {
+	from, ok := r.From.(*IntegerLiteral)
+	if !ok {
+		return 1
+	}
+	to, ok := r.To.(*IntegerLiteral)
+	if !ok {
+		return 1
+	}
+	n := uint64(to.Value) - uint64(from.Value)
+	if to.Value < from.Value {
+		n = uint64(from.Value) - uint64(to.Value)
+	}
+	if n >= maxRangeLength {
+		return maxRangeLength
+	}
+	return int(n) + 1
+}
+
+func countAll(exprs []Expression) int {
+	n := 0
+	for _, expr := range exprs {
+		n += CountOperations(expr)
+	}
+	return n
+}
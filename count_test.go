@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestCountOperations(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"42", 0},
+		{"x", 0},
+		{"1+2*3", 2},
+		{"-(1 + 2)", 2},
+		{"5! + 50%", 3},
+		{"f(1 + 2, 3 * 4)", 3},
+		{"abs(abs(1))", 2},
+		{"1..10", 10},
+		{"10..1", 10},
+		{"sum(1..999999)", 1_000_000},
+		{"1..x", 1},
+		{"1..(2 + 3)", 2},
+		{"9223372036854775807..0", maxRangeLength},
+		{"-1..1", 2},
+		{"(1 + 2) as float", 1},
+	}
+	for _, tt := range tests {
+		if got := CountOperations(mustParse(t, tt.input)); got != tt.want {
+			t.Errorf("CountOperations(%s) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+	if got := CountOperations(Flatten(mustParse(t, "1 + 2 + 3 + 4"))); got != 3 {
+		t.Errorf("CountOperations of a flattened chain = %d, want 3", got)
+	}
+}
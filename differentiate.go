@@ -0,0 +1,76 @@
+package main
+
+import "fmt"
+
+// Differentiate returns the derivative of expr with respect to variable,
+// simplified with Simplify. It applies the sum and product rules, and the
+// power rule for integer-literal exponents. Identifiers other than variable
+// are constants. Division, calls and other nodes with no
+// rule are reported as errors.
+func Differentiate(expr Expression, variable string) (Expression, error) {
+	d, err := derivative(expr, variable)
+	if err != nil {
+		return nil, err
+	}
+	return Simplify(d), nil
+}
+
+func derivative(expr Expression, variable string) (Expression, error) {
+	switch e := expr.(type) {
+	case *IntegerLiteral, *FloatLiteral:
+		return &IntegerLiteral{Value: 0, Position: e.Pos(), EndPosition: e.End()}, nil
+	case *Identifier:
+		v := 0
+		if e.Name == variable {
+			v = 1
+		}
+		return &IntegerLiteral{Value: v, Position: e.Position, EndPosition: e.EndPosition}, nil
+	case *UnaryExpression:
+		if e.Op != SUB || e.Postfix {
+			break
+		}
+		d, err := derivative(e.Operand, variable)
+		if err != nil {
+			return nil, err
+		}
+		copied := *e
+		copied.Operand = d
+		return &copied, nil
+	case *BinaryExpression:
+		if n, ok := e.Right.(*IntegerLiteral); ok && e.Op == POW {
+			du, err := derivative(e.Left, variable)
+			if err != nil {
+				return nil, err
+			}
+			// (u^n)' = n * u^(n-1) * u'
+			binary := func(left Expression, op Token, right Expression) *BinaryExpression {
+				return &BinaryExpression{Left: left, Op: op, Right: right, Position: e.Position, EndPosition: e.EndPosition}
+			}
+			lit := func(v int) *IntegerLiteral {
+				return &IntegerLiteral{Value: v, Position: n.Position, EndPosition: n.EndPosition}
+			}
+			return binary(binary(lit(n.Value), MUL, binary(e.Left, POW, lit(n.Value-1))), MUL, du), nil
+		}
+		if e.Op != ADD && e.Op != SUB && e.Op != MUL {
+			break
+		}
+		dl, err := derivative(e.Left, variable)
+		if err != nil {
+			return nil, err
+		}
+		dr, err := derivative(e.Right, variable)
+		if err != nil {
+			return nil, err
+		}
+		binary := func(left Expression, op Token, right Expression) *BinaryExpression {
+			return &BinaryExpression{Left: left, Op: op, Right: right, Position: e.Position, EndPosition: e.EndPosition}
+		}
+		if e.Op == MUL {
+			// (uv)' = u'v + uv'
+			return binary(binary(dl, MUL, e.Right), ADD, binary(e.Left, MUL, dr)), nil
+		}
+		return binary(dl, e.Op, dr), nil
+	}
+	pos := expr.Pos()
+	return nil, fmt.Errorf("cannot differentiate %s at %s", expr, pos)
+}
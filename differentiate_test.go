@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestDifferentiate(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"x*x", "2 * x"},
+		{"3", "0"},
+		{"y", "0"},
+		{"x", "1"},
+		{"x + y", "1"},
+		{"3 * x", "3"},
+		{"x ^ 3", "3 * x ^ 2"},
+		{"-x", "-1"},
+		{"x * y", "y"},
+	}
+	for _, tt := range tests {
+		got, err := Differentiate(mustParse(t, tt.input), "x")
+		if err != nil {
+			t.Fatalf("Differentiate(%s): %v", tt.input, err)
+		}
+		if want := mustParse(t, tt.want); !Equal(got, want) {
+			t.Errorf("d/dx %s = %s, want %s", tt.input, got, want)
+		}
+	}
+}
+
+func TestDifferentiateUnsupported(t *testing.T) {
+	for _, input := range []string{"1 / x", "f(x)", "x ^ y"} {
+		if d, err := Differentiate(mustParse(t, input), "x"); err == nil {
+			t.Errorf("Differentiate(%s) = %s, want an error", input, d)
+		}
+	}
+}
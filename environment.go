@@ -0,0 +1,67 @@
+package main
+
+import "sort"
+
+// Environment is one scope in a chain of variable and function bindings.
+// Lookups that miss in a scope continue in its parent.
+type Environment struct {
+	vars   map[string]Value
+	funcs  map[string]*closure
+	parent *Environment
+}
+
+// closure pairs a function with the environment it was defined in, so its
+// body resolves free names lexically rather than from the caller.
+type closure struct {
+	def *FunctionDefinition
+	env *Environment
+}
+
+// NewEnvironment returns an empty scope nested inside parent, which may be
+// nil for a global scope.
+func NewEnvironment(parent *Environment) *Environment {
+	return &Environment{
+		vars:   make(map[string]Value),
+		funcs:  make(map[string]*closure),
+		parent: parent,
+	}
+}
+
+// Get looks up a variable, starting in e and walking outwards.
+func (e *Environment) Get(name string) (Value, bool) {
+	for env := e; env != nil; env = env.parent {
+		if v, ok := env.vars[name]; ok {
+			return v, true
+		}
+	}
+	return Value{}, false
+}
+
+// Set binds a variable in e itself, shadowing any outer binding.
+func (e *Environment) Set(name string, v Value) {
+	e.vars[name] = v
+}
+
+// Define binds a function in e, closing over e.
+func (e *Environment) Define(def *FunctionDefinition) {
+	e.funcs[def.Name] = &closure{def: def, env: e}
+}
+
+// Names returns the variables bound in e itself, sorted.
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.vars))
+	for name := range e.vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (e *Environment) lookupFunc(name string) (*closure, bool) {
+	for env := e; env != nil; env = env.parent {
+		if fn, ok := env.funcs[name]; ok {
+			return fn, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParameterShadowsGlobal(t *testing.T) {
+	ev := newEvaluator(nil)
+	program, err := ParseProgram("x = 10; def double(x) = x * 2; double(3)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := ev.run(program)
+	if err != nil || v.Int != 6 {
+		t.Fatalf("double(3) = %s, %v, want 6", v, err)
+	}
+	if x, _ := ev.env.Get("x"); x.Int != 10 {
+		t.Errorf("global x = %s after the call, want 10", x)
+	}
+	if got := ev.env.Names(); !reflect.DeepEqual(got, []string{"x"}) {
+		t.Errorf("global names = %v, want [x]: the parameter leaked", got)
+	}
+}
+
+func TestFunctionsSeeDefinitionScope(t *testing.T) {
+	// scale reads the global k, not the caller's parameter of the same name.
+	v, err := evalProgram(t, "k = 3; def scale(n) = n * k; def call(k) = scale(k); call(5)")
+	if err != nil || v.Int != 15 {
+		t.Errorf("call(5) = %s, %v, want 15", v, err)
+	}
+}
+
+func TestEnvironmentChain(t *testing.T) {
+	global := NewEnvironment(nil)
+	global.Set("x", Value{Kind: IntValue, Int: 1})
+	inner := NewEnvironment(global)
+	inner.Set("x", Value{Kind: IntValue, Int: 2})
+	inner.Set("y", Value{Kind: IntValue, Int: 3})
+
+	if v, _ := inner.Get("x"); v.Int != 2 {
+		t.Errorf("inner x = %s, want 2", v)
+	}
+	if v, _ := global.Get("x"); v.Int != 1 {
+		t.Errorf("global x = %s, want 1", v)
+	}
+	if _, ok := global.Get("y"); ok {
+		t.Errorf("global sees inner y")
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+// Equal reports whether a and b are structurally identical expression trees.
+// Positions are ignored.
+func Equal(a, b Expression) bool {
+	switch x := a.(type) {
+	case *BinaryExpression:
+		y, ok := b.(*BinaryExpression)
+		return ok && x.Op == y.Op && Equal(x.Left, y.Left) && Equal(x.Right, y.Right)
+	case *NaryExpression:
+		y, ok := b.(*NaryExpression)
+		return ok && x.Op == y.Op && equalAll(x.Operands, y.Operands)
+	case *UnaryExpression:
+		y, ok := b.(*UnaryExpression)
+		return ok && x.Op == y.Op && x.Postfix == y.Postfix && Equal(x.Operand, y.Operand)
+	case *IntegerLiteral:
+		y, ok := b.(*IntegerLiteral)
+		return ok && x.Value == y.Value
+	case *FloatLiteral:
+		y, ok := b.(*FloatLiteral)
+		return ok && x.Value == y.Value
+	case *CastExpression:
+		y, ok := b.(*CastExpression)
+		return ok && x.Type == y.Type && Equal(x.Operand, y.Operand)
+	case *RangeExpression:
+		y, ok := b.(*RangeExpression)
+		return ok && Equal(x.From, y.From) && Equal(x.To, y.To)
+	case *Identifier:
+		y, ok := b.(*Identifier)
+		return ok && x.Name == y.Name
+	case *StringLiteral:
+		y, ok := b.(*StringLiteral)
+		return ok && x.Value == y.Value
+	case *ExpressionList:
+		y, ok := b.(*ExpressionList)
+		return ok && equalAll(x.Elements, y.Elements)
+	case *CallExpression:
+		y, ok := b.(*CallExpression)
+		return ok && x.Function == y.Function && equalAll(x.Arguments, y.Arguments)
+	case *Assignment:
+		y, ok := b.(*Assignment)
+		return ok && x.Name == y.Name && Equal(x.Value, y.Value)
+	case *FunctionDefinition:
+		y, ok := b.(*FunctionDefinition)
+		if !ok || x.Name != y.Name || len(x.Parameters) != len(y.Parameters) {
+			return false
+		}
+		for i := range x.Parameters {
+			if x.Parameters[i] != y.Parameters[i] {
+				return false
+			}
+		}
+		return Equal(x.Body, y.Body)
+	default:
+		return a == nil && b == nil
+	}
+}
+
+func equalAll(xs, ys []Expression) bool {
+	if len(xs) != len(ys) {
+		return false
+	}
+	for i := range xs {
+		if !Equal(xs[i], ys[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NaryExpression applies one associative operator, + or *, to a run of
+// operands, as Flatten produces from a chain like 1 + 2 + 3 + 4. It evaluates
+// like the left-nested chain Unflatten turns it back into.
+type NaryExpression struct {
+	Op          Token
+	Operands    []Expression
+	Position    Position
+	EndPosition Position
+}
+
+func (*NaryExpression) exprNode() {}
+
+func (ne *NaryExpression) Pos() Position {
+	return ne.Position
+}
+
+func (ne *NaryExpression) End() Position {
+	return ne.EndPosition
+}
+
+func (ne *NaryExpression) String() string {
+	operands := make([]string, len(ne.Operands))
+	for i, operand := range ne.Operands {
+		operands[i] = operand.String()
+	}
+	return fmt.Sprintf("(%s)", strings.Join(operands, " "+ne.Symbol()+" "))
+}
+
+// Symbol returns the operator as written, like BinaryExpression.Symbol.
+func (ne *NaryExpression) Symbol() string {
+	return TokenName(ne.Op)
+}
+
+// Flatten returns a copy of expr in which every left-nested chain of three
+// or more operands joined by the same + or * becomes one NaryExpression, so
+// 1 + 2 + 3 + 4 has a single node with four operands and 1 + 2 * 3 + 4 has
+// an n-ary + whose middle operand is still the binary 2 * 3. Chains are only
+// followed down the left, the way the parser builds them, so that Unflatten
+// restores an Equal tree.
+func Flatten(expr Expression) Expression {
+	return Replace(expr, isChain, func(n Node) Node {
+		be := n.(*BinaryExpression)
+		var operands []Expression
+		var chain Expression = be
+		for {
+			link, ok := chain.(*BinaryExpression)
+			if !ok || link.Op != be.Op {
+				break
+			}
+			operands = append(operands, Flatten(link.Right))
+			chain = link.Left
+		}
+		operands = append(operands, Flatten(chain))
+		for i, j := 0, len(operands)-1; i < j; i, j = i+1, j-1 {
+			operands[i], operands[j] = operands[j], operands[i]
+		}
+		return &NaryExpression{Op: be.Op, Operands: operands, Position: be.Position, EndPosition: be.EndPosition}
+	}).(Expression)
+}
+
+func isChain(n Node) bool {
+	be, ok := n.(*BinaryExpression)
+	if !ok || (be.Op != ADD && be.Op != MUL) {
+		return false
+	}
+	left, ok := be.Left.(*BinaryExpression)
+	return ok && left.Op == be.Op
+}
+
+// Unflatten is the inverse of Flatten: it returns a copy of expr with every
+// NaryExpression replaced by the left-nested chain of binary expressions it
+// stands for.
+func Unflatten(expr Expression) Expression {
+	return Replace(expr, func(n Node) bool {
+		_, ok := n.(*NaryExpression)
+		return ok
+	}, func(n Node) Node {
+		ne := n.(*NaryExpression)
+		if len(ne.Operands) == 0 {
+			return &NaryExpression{Op: ne.Op, Position: ne.Position, EndPosition: ne.EndPosition}
+		}
+		chain := Unflatten(ne.Operands[0])
+		for _, operand := range ne.Operands[1:] {
+			chain = &BinaryExpression{Left: chain, Op: ne.Op, Right: Unflatten(operand), Position: ne.Position, EndPosition: operand.End()}
+		}
+		return chain
+	}).(Expression)
+}
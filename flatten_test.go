@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestFlatten(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1+2+3+4", "(1 + 2 + 3 + 4)"},
+		{"1+2*3+4", "(1 + (2 * 3) + 4)"},
+		{"a*b*c + 1", "((a * b * c) + 1)"},
+		{"1 + 2", "(1 + 2)"},
+		{"1 - 2 - 3", "((1 - 2) - 3)"},
+		{"1 + (2 + 3) + 4", "(1 + (2 + 3) + 4)"},
+	}
+	for _, tt := range tests {
+		expr := mustParse(t, tt.input)
+		flat := Flatten(expr)
+		if got := flat.String(); got != tt.want {
+			t.Errorf("Flatten(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+		if back := Unflatten(flat); !Equal(back, expr) {
+			t.Errorf("Unflatten(Flatten(%q)) = %s, want %s", tt.input, back, expr)
+		}
+	}
+}
+
+func TestFlattenOperands(t *testing.T) {
+	ne, ok := Flatten(mustParse(t, "1+2+3+4")).(*NaryExpression)
+	if !ok {
+		t.Fatalf("Flatten(1+2+3+4) is not an NaryExpression")
+	}
+	if ne.Op != ADD || len(ne.Operands) != 4 {
+		t.Errorf("Flatten(1+2+3+4) = %s with %d operands, want four of +", ne.Symbol(), len(ne.Operands))
+	}
+	v, err := EvaluateValue(ne, nil)
+	if err != nil || v.String() != "10" {
+		t.Errorf("EvaluateValue(%s) = %s, %v, want 10", ne, v, err)
+	}
+}
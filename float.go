@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// constants are predeclared variables. Bindings in the environment take
+// precedence over them.
+var constants = map[string]Value{
+	"nan": {Kind: FloatValue, Float: math.NaN()},
+	"inf": {Kind: FloatValue, Float: math.Inf(1)},
+}
+
+// formatFloat formats f so that it reads back as a float: integral values
+// keep a trailing ".0" to tell them apart from integers, and the special
+// values use the names of the constants. Negative zero, as from 0.0 * -1,
+// prints as "0.0"; it compares equal to zero anyway.
+func formatFloat(f float64) string {
+	switch {
+	case f == 0:
+		return "0.0"
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+// formatScientific formats f with an exponent, as 1.5e+20, using as many
+// digits as it takes to read back exactly. The special values and zero look
+// as they do in formatFloat.
+func formatScientific(f float64) string {
+	if f == 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+		return formatFloat(f)
+	}
+	return strconv.FormatFloat(f, 'e', -1, 64)
+}
+
+// isFloatOperand reports whether v can take part in float arithmetic with
+// other. An int is promoted when the other operand is a float.
+func isFloatOperand(v, other Value) bool {
+	return (v.Kind == FloatValue || v.Kind == IntValue) && (v.Kind == FloatValue || other.Kind == FloatValue)
+}
+
+// float returns v's numeric payload as a float64.
+func (v Value) float() float64 {
+	switch v.Kind {
+	case FloatValue:
+		return v.Float
+	case BigValue:
+		f, _ := new(big.Float).SetInt(v.Big).Float64()
+		return f
+	}
+	return float64(v.Int)
+}
+
+func applyFloatOp(op Token, left, right float64) (Value, error) {
+	var f float64
+	switch op {
+	case ADD:
+		f = left + right
+	case SUB:
+		f = left - right
+	case MUL:
+		f = left * right
+	case DIV:
+		if right == 0 {
+			return Value{}, fmt.Errorf("division by zero")
+		}
+		f = left / right
+	case MOD:
+		if right == 0 {
+			return Value{}, fmt.Errorf("modulo by zero")
+		}
+		f = math.Mod(left, right)
+	case POW:
+		f = math.Pow(left, right)
+	default:
+		return Value{}, fmt.Errorf("unknown operator")
+	}
+	return Value{Kind: FloatValue, Float: f}, nil
+}
+
+// cast converts v to typ, "int" or "float". Floats become ints by truncating
+// toward zero.
+func cast(v Value, typ string) (Value, error) {
+	switch {
+	case typ == "float" && v.Kind == FloatValue:
+		return v, nil
+	case typ == "float" && v.Kind == IntValue:
+		return Value{Kind: FloatValue, Float: float64(v.Int)}, nil
+	case typ == "float" && v.Kind == BigValue:
+		return Value{Kind: FloatValue, Float: v.float()}, nil
+	case typ == "int" && v.Kind == IntValue:
+		return v, nil
+	case typ == "int" && v.Kind == FloatValue:
+		t := math.Trunc(v.Float)
+		if math.IsNaN(t) || t < math.MinInt || t >= math.MaxInt {
+			return Value{}, fmt.Errorf("float %s does not fit in an int", v)
+		}
+		return Value{Kind: IntValue, Int: int(t)}, nil
+	case typ == "int" && v.Kind == BigValue:
+		if !v.Big.IsInt64() || v.Big.Int64() < math.MinInt || v.Big.Int64() > math.MaxInt {
+			return Value{}, fmt.Errorf("bigint %s does not fit in an int", v)
+		}
+		return Value{Kind: IntValue, Int: int(v.Big.Int64())}, nil
+	}
+	return Value{}, fmt.Errorf("cannot convert %s %s to %s", v.Kind, v.Quote(), typ)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCast(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Value
+	}{
+		{"3 as float", Value{Kind: FloatValue, Float: 3}},
+		{"3.9 as int", Value{Kind: IntValue, Int: 3}},
+		{"-3.9 as int", Value{Kind: IntValue, Int: -3}},
+		{"(7 as float) / 2", Value{Kind: FloatValue, Float: 3.5}},
+		{"2 as int", Value{Kind: IntValue, Int: 2}},
+	}
+	for _, tt := range tests {
+		got, err := EvalValue(tt.input)
+		if err != nil {
+			t.Fatalf("EvalValue(%q): %v", tt.input, err)
+		}
+		if got.Kind != tt.want.Kind || got.Int != tt.want.Int || got.Float != tt.want.Float {
+			t.Errorf("EvalValue(%q) = %s %s, want %s %s", tt.input, got.Kind, got, tt.want.Kind, tt.want)
+		}
+	}
+}
+
+func TestNaNAndInf(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"inf", "inf"},
+		{"-inf", "-inf"},
+		{"inf + 1", "inf"},
+		{"inf + 1 == inf", "true"},
+		{"-inf < 0", "true"},
+		{"inf - inf", "nan"},
+		{"nan + 1", "nan"},
+		{"nan * 0", "nan"},
+		{"nan == nan", "false"},
+		{"nan != nan", "true"},
+		{"nan < 1", "false"},
+		{"nan >= 1", "false"},
+	}
+	for _, tt := range tests {
+		v, err := EvalValue(tt.input)
+		if err != nil || v.String() != tt.want {
+			t.Errorf("EvalValue(%q) = %s, %v, want %s", tt.input, v, err, tt.want)
+		}
+	}
+}
+
+func TestNegativeZero(t *testing.T) {
+	checkResults(t, []struct{ input, want string }{
+		{"-0.0", "0.0"},
+		{"0.0 * -1", "0.0"},
+		{"-1.0 * 0", "0.0"},
+		{"0.0 / -5", "0.0"},
+		{"1 / -inf", "0.0"},
+		{"-0.0 == 0.0", "true"},
+		{"-0.0 < 0.0", "false"},
+	})
+	if got := (Value{Kind: FloatValue, Float: math.Copysign(0, -1)}).String(); got != "0.0" {
+		t.Errorf("String of -0 = %s, want 0.0", got)
+	}
+}
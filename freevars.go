@@ -0,0 +1,45 @@
+package main
+
+import "sort"
+
+// FreeVars returns the sorted names of the variables expr needs bound before
+// it can be evaluated, such as [x y] for x + y*x. Parameters are bound within
+// their function's body, and the built-in constants nan and inf are never
+// free.
+func FreeVars(expr Expression) []string {
+	free := make(map[string]bool)
+	freeVars(expr, nil, free)
+
+	names := make([]string, 0, len(free))
+	for name := range free {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func freeVars(expr Expression, bound map[string]bool, free map[string]bool) {
+	switch e := expr.(type) {
+	case *Identifier:
+		if _, ok := constants[e.Name]; !ok && !bound[e.Name] {
+			free[e.Name] = true
+		}
+	case *FunctionDefinition:
+		inner := make(map[string]bool, len(bound)+len(e.Parameters))
+		for name := range bound {
+			inner[name] = true
+		}
+		for _, param := range e.Parameters {
+			inner[param] = true
+		}
+		freeVars(e.Body, inner, free)
+	default:
+		Inspect(expr, func(n Node) bool {
+			if n == expr {
+				return true
+			}
+			freeVars(n.(Expression), bound, free)
+			return false
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFreeVars(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"x + y*x", []string{"x", "y"}},
+		{"1 + 2 * 3", []string{}},
+		{"inf - nan", []string{}},
+		{"f(b, a) + b", []string{"a", "b"}},
+		{"(z, y)..x", []string{"x", "y", "z"}},
+	}
+	for _, tt := range tests {
+		if got := FreeVars(mustParse(t, tt.input)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FreeVars(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestFreeVarsParameters(t *testing.T) {
+	program, err := ParseProgram("def f(a, x) = a * x + b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	def, ok := program.Statements[0].(*FunctionDefinition)
+	if !ok {
+		t.Fatalf("statement is %T, want *FunctionDefinition", program.Statements[0])
+	}
+	if got, want := FreeVars(def), []string{"b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FreeVars(%s) = %q, want %q", def, got, want)
+	}
+}
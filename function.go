@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CallExpression calls a function by name, e.g. square(5).
+type CallExpression struct {
+	Function    string
+	Arguments   []Expression
+	Position    Position
+	EndPosition Position
+}
+
+func (*CallExpression) exprNode() {}
+
+func (ce *CallExpression) Pos() Position {
+	return ce.Position
+}
+
+func (ce *CallExpression) End() Position {
+	return ce.EndPosition
+}
+
+func (ce *CallExpression) String() string {
+	args := make([]string, len(ce.Arguments))
+	for i, arg := range ce.Arguments {
+		args[i] = arg.String()
+	}
+	return ce.Function + "(" + strings.Join(args, ", ") + ")"
+}
+
+// FunctionDefinition is a statement such as def square(x) = x * x.
+type FunctionDefinition struct {
+	Name        string
+	Parameters  []string
+	Body        Expression
+	Position    Position
+	EndPosition Position
+}
+
+func (*FunctionDefinition) exprNode() {}
+
+func (fd *FunctionDefinition) Pos() Position {
+	return fd.Position
+}
+
+func (fd *FunctionDefinition) End() Position {
+	return fd.EndPosition
+}
+
+func (fd *FunctionDefinition) String() string {
+	return fmt.Sprintf("def %s(%s) = %s", fd.Name, strings.Join(fd.Parameters, ", "), fd.Body)
+}
+
+// parseCall parses the argument list of a call to name, whose identifier
+// started at pos.
+func parseCall(p *parser, pos Position, name string) (Expression, error) {
+	args, err := parseArgs(p)
+	if err != nil {
+		return nil, err
+	}
+	return &CallExpression{Function: name, Arguments: args, Position: pos, EndPosition: p.end}, nil
+}
+
+// parseArgs parses a parenthesized, comma-separated argument list, which may
+// be empty, as in f(). A trailing comma, as in f(1, 2,), is an error.
+func parseArgs(p *parser) ([]Expression, error) {
+	if err := expect(p, LPAREN); err != nil {
+		return nil, err
+	}
+	if _, tok, _ := p.Peek(); tok == RPAREN {
+		p.Lex()
+		return nil, nil
+	}
+
+	var args []Expression
+	for {
+		arg, err := parseExpression(p)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+
+		pos, tok, _ := p.Peek()
+		switch {
+		case tok == COMMA:
+			p.Lex()
+			if _, tok, _ := p.Peek(); tok == RPAREN {
+				return nil, fmt.Errorf("trailing comma in argument list at %s", pos)
+			}
+		case tok == RPAREN, isCloser(tok):
+			if err := expect(p, RPAREN); err != nil {
+				return nil, err
+			}
+			return args, nil
+		default:
+			return nil, expected(pos, tok, ", or ) after argument")
+		}
+	}
+}
+
+// parseFunctionDefinition parses def name(param, ...) = body.
+func parseFunctionDefinition(p *parser) (Expression, error) {
+	pos, _, _ := p.Lex()
+
+	namePos, tok, name := p.Lex()
+	if tok != IDENT {
+		return nil, expected(namePos, tok, "function name")
+	}
+	if err := expect(p, LPAREN); err != nil {
+		return nil, err
+	}
+
+	def := &FunctionDefinition{Name: name, Position: pos}
+	if _, tok, _ := p.Peek(); tok != RPAREN {
+		for {
+			paramPos, tok, param := p.Lex()
+			if tok != IDENT {
+				return nil, expected(paramPos, tok, "parameter name")
+			}
+			def.Parameters = append(def.Parameters, param)
+
+			if _, tok, _ := p.Peek(); tok != COMMA {
+				break
+			}
+			p.Lex()
+		}
+	}
+	if err := expect(p, RPAREN); err != nil {
+		return nil, err
+	}
+	if err := expect(p, ASSIGN); err != nil {
+		return nil, err
+	}
+
+	body, err := parseExpression(p)
+	if err != nil {
+		return nil, err
+	}
+	def.Body = body
+	def.EndPosition = p.end
+	return def, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// evalProgram parses and evaluates a whole program.
+func evalProgram(t *testing.T, input string) (Value, error) {
+	t.Helper()
+	program, err := ParseProgram(input)
+	if err != nil {
+		t.Fatalf("ParseProgram(%q): %v", input, err)
+	}
+	return EvaluateProgram(program, nil)
+}
+
+func TestUserFunctions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"def square(x) = x * x; square(5)", "25"},
+		{"def add(a, b) = a + b; add(2, 3) * 2", "10"},
+		{"def seven() = 7; seven()", "7"},
+		{"def fact(n) = if(n, n * fact(n - 1), 1); fact(10)", "3628800"},
+	}
+	for _, tt := range tests {
+		v, err := evalProgram(t, tt.input)
+		if err != nil || v.String() != tt.want {
+			t.Errorf("%q = %s, %v, want %s", tt.input, v, err, tt.want)
+		}
+	}
+}
+
+func TestUserFunctionErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"def square(x) = x * x; square(1, 2)", "square expects 1 argument, got 2"},
+		{"def add(a, b) = a + b; add(1)", "add expects 2 arguments, got 1"},
+		{"def loop(n) = loop(n); loop(1)", "maximum call depth of 1000 exceeded calling loop"},
+		{"nope(1)", "undefined function nope"},
+	}
+	for _, tt := range tests {
+		_, err := evalProgram(t, tt.input)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%q error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"()", nil},
+		{"(x)", []string{"x"}},
+		{"(1, y + 2, f(3))", []string{"1", "(y + 2)", "f(3)"}},
+	}
+	for _, tt := range tests {
+		args, err := parseArgs(newParser(newStringLexer(tt.input)))
+		if err != nil {
+			t.Errorf("parseArgs(%q): %v", tt.input, err)
+			continue
+		}
+		var got []string
+		for _, arg := range args {
+			got = append(got, arg.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseArgs(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseArgsErrors(t *testing.T) {
+	checkParseErrors(t, []struct{ input, want string }{
+		{"f(1 2)", "expected , or ) after argument at 1:5"},
+		{"f(1, 2,)", "trailing comma in argument list at 1:7"},
+		{"f(1,", "unexpected end of input at 1:5"},
+	})
+}
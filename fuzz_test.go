@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// FuzzParse checks that no input makes the lexer, parser or evaluator panic.
+// The seeds in testdata/fuzz/FuzzParse are inputs that once crashed them.
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{"1 + 2 * 3", "-", "|x", "'a", "\"s", "1..3", "def f(x) = f(x); f(1)", "0x", "1e", "9!!", "50% -", "\\"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		if expr, err := Parse(input); err == nil {
+			_ = expr.String()
+			EvaluateValue(expr, nil)
+		}
+		if program, err := ParseProgram(input); program != nil {
+			_ = program.String()
+			if err == nil {
+				EvaluateProgram(program, nil)
+			}
+		}
+		state := StartState
+		for _, line := range strings.Split(input, "\n") {
+			_, state = LexLine(line, state)
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"math"
+)
+
+// Hash returns an FNV-1a hash of the structure of n: node types, operators,
+// names, literal values and children, but not positions. Trees that are Equal
+// hash the same, so Hash can key a cache of Equal-deduplicated expressions.
+func Hash(n Node) uint64 {
+	h := hasher{fnv.New64a()}
+	h.node(n)
+	return h.Sum64()
+}
+
+type hasher struct {
+	hash.Hash64
+}
+
+// Tags distinguish node types, so that, say, the integer 1 and the string
+// "\x01" do not share an encoding.
+const (
+	hashNil byte = iota
+	hashProgram
+	hashBinary
+	hashUnary
+	hashInteger
+	hashFloat
+	hashString
+	hashIdentifier
+	hashCast
+	hashRange
+	hashList
+	hashCall
+	hashAssignment
+	hashFunction
+	hashNary
+)
+
+func (h hasher) node(n Node) {
+	switch e := n.(type) {
+	case *Program:
+		h.tag(hashProgram)
+		h.nodes(e.Statements)
+	case *BinaryExpression:
+		h.tag(hashBinary)
+		h.int(int(e.Op))
+		h.node(e.Left)
+		h.node(e.Right)
+	case *NaryExpression:
+		h.tag(hashNary)
+		h.int(int(e.Op))
+		h.nodes(e.Operands)
+	case *UnaryExpression:
+		h.tag(hashUnary)
+		h.int(int(e.Op))
+		if e.Postfix {
+			h.int(1)
+		} else {
+			h.int(0)
+		}
+		h.node(e.Operand)
+	case *IntegerLiteral:
+		h.tag(hashInteger)
+		h.int(e.Value)
+	case *FloatLiteral:
+		h.tag(hashFloat)
+		f := e.Value
+		if f == 0 {
+			f = 0 // -0 is Equal to 0
+		}
+		h.int(int(math.Float64bits(f)))
+	case *StringLiteral:
+		h.tag(hashString)
+		h.string(e.Value)
+	case *Identifier:
+		h.tag(hashIdentifier)
+		h.string(e.Name)
+	case *CastExpression:
+		h.tag(hashCast)
+		h.string(e.Type)
+		h.node(e.Operand)
+	case *RangeExpression:
+		h.tag(hashRange)
+		h.node(e.From)
+		h.node(e.To)
+	case *ExpressionList:
+		h.tag(hashList)
+		h.nodes(e.Elements)
+	case *CallExpression:
+		h.tag(hashCall)
+		h.string(e.Function)
+		h.nodes(e.Arguments)
+	case *Assignment:
+		h.tag(hashAssignment)
+		h.string(e.Name)
+		h.node(e.Value)
+	case *FunctionDefinition:
+		h.tag(hashFunction)
+		h.string(e.Name)
+		h.int(len(e.Parameters))
+		for _, param := range e.Parameters {
+			h.string(param)
+		}
+		h.node(e.Body)
+	default:
+		h.tag(hashNil)
+	}
+}
+
+func (h hasher) nodes(exprs []Expression) {
+	h.int(len(exprs))
+	for _, expr := range exprs {
+		h.node(expr)
+	}
+}
+
+func (h hasher) tag(t byte) {
+	h.Write([]byte{t})
+}
+
+func (h hasher) int(n int) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(n))
+	h.Write(buf[:])
+}
+
+// string writes s with its length first, so that adjacent strings cannot run
+// into each other.
+func (h hasher) string(s string) {
+	h.int(len(s))
+	h.Write([]byte(s))
+}
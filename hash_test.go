@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestHashEqualTrees(t *testing.T) {
+	tests := []struct{ a, b string }{
+		{"1 + 2 * x", "1+2*x"},
+		{"(1 + 2)", "1 + 2"},
+		{"f(x, 1)", "f( x ,1 )"},
+		{"1..3", "1 .. 3"},
+	}
+	for _, tt := range tests {
+		a, b := mustParse(t, tt.a), mustParse(t, tt.b)
+		if !Equal(a, b) {
+			t.Fatalf("%q and %q are not Equal", tt.a, tt.b)
+		}
+		if Hash(a) != Hash(b) {
+			t.Errorf("Hash(%q) != Hash(%q)", tt.a, tt.b)
+		}
+	}
+}
+
+func TestHashDifferentTrees(t *testing.T) {
+	inputs := []string{
+		"1 + 2", "2 + 1", "1 - 2", "1 + 3", "x + 2", "y + 2",
+		"1.0 + 2", "-1", "1", "f(1)", "g(1)", "f(1, 2)", "(1, 2)",
+		"1..2", "1 as float", `"1"`,
+	}
+	seen := make(map[uint64]string)
+	for _, input := range inputs {
+		h := Hash(mustParse(t, input))
+		if prev, ok := seen[h]; ok {
+			t.Errorf("Hash(%q) == Hash(%q)", input, prev)
+		}
+		seen[h] = input
+	}
+}
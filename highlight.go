@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+)
+
+// DefaultColors are the ANSI escape sequences Highlight uses for each kind of
+// token. Kinds without an entry are left uncolored.
+var DefaultColors = map[TokenKind]string{
+	KindIllegal:    "\x1b[31m", // red
+	KindIdentifier: "\x1b[34m", // blue
+	KindKeyword:    "\x1b[35m", // magenta
+	KindLiteral:    "\x1b[36m", // cyan
+	KindOperator:   "\x1b[33m", // yellow
+	KindComment:    "\x1b[90m", // grey
+}
+
+const ansiReset = "\x1b[0m"
+
+// A Highlighter colors source text token by token.
+type Highlighter struct {
+	// Colors maps token kinds to the escape sequences that start them. A
+	// Highlighter with no Colors returns its input unchanged, which turns
+	// coloring off without changing the caller.
+	Colors map[TokenKind]string
+}
+
+// Highlight colors src with DefaultColors.
+func Highlight(src string) (string, error) {
+	return Highlighter{Colors: DefaultColors}.Highlight(src)
+}
+
+// Highlight wraps each token of src in the escape sequence for its kind,
+// comments included. Everything between tokens, such as white space, is
+// copied through unchanged.
+func (h Highlighter) Highlight(src string) (string, error) {
+	l := NewLexer(bufio.NewReader(strings.NewReader(src)))
+	l.EmitTrivia = true
+	toks := l.Tokens()
+	if err := l.Err(); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	prev := 0
+	for _, t := range toks {
+		sb.WriteString(src[prev:t.Pos.offset])
+		text := src[t.Pos.offset:t.End.offset]
+		if color, ok := h.Colors[t.Token.Kind()]; ok {
+			sb.WriteString(color + text + ansiReset)
+		} else {
+			sb.WriteString(text)
+		}
+		prev = t.End.offset
+	}
+	sb.WriteString(src[prev:])
+	return sb.String(), nil
+}
@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestHighlight(t *testing.T) {
+	got, err := Highlight("12 +  x // c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "\x1b[36m12\x1b[0m \x1b[33m+\x1b[0m  \x1b[34mx\x1b[0m \x1b[90m// c\x1b[0m"
+	if got != want {
+		t.Errorf("Highlight = %q, want %q", got, want)
+	}
+}
+
+func TestHighlightWithoutColors(t *testing.T) {
+	const src = "let x = 1 +\t2 // c\n"
+	got, err := Highlighter{}.Highlight(src)
+	if err != nil || got != src {
+		t.Errorf("Highlight without colors = %q, %v, want %q", got, err, src)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+// EvaluateIterative evaluates expr like evaluateExpression, but walks the tree
+// with explicit stacks instead of recursion, so deep trees do not grow the Go
+// stack. Operands are visited left to right and combined by the same value
+// operations, so results and errors match the recursive evaluator.
+func EvaluateIterative(expr Expression, env map[string]int) (int, error) {
+	type frame struct {
+		expr     Expression
+		expanded bool
+	}
+
+	ev := newEvaluator(env)
+	work := []frame{{expr: expr}}
+	var operands []Value
+
+	for len(work) > 0 {
+		f := work[len(work)-1]
+		work = work[:len(work)-1]
+
+		be, ok := f.expr.(*BinaryExpression)
+		if !ok {
+			v, err := ev.eval(f.expr)
+			if err != nil {
+				return 0, err
+			}
+			operands = append(operands, v)
+			continue
+		}
+
+		if !f.expanded {
+			// Revisit the operator once both operands are on the stack.
+			work = append(work, frame{expr: be, expanded: true}, frame{expr: be.Right}, frame{expr: be.Left})
+			continue
+		}
+
+		left, right := operands[len(operands)-2], operands[len(operands)-1]
+		operands = operands[:len(operands)-2]
+		v, err := ev.binary(be, left, right)
+		if err != nil {
+			return 0, err
+		}
+		operands = append(operands, v)
+	}
+
+	return operands[0].integer()
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// randomExpression returns a random tree of at most depth levels over small
+// integer literals, the variables x, y and z and the arithmetic and
+// comparison operators, so that it exercises division by zero, negative
+// exponents and non-integer results as well as plain arithmetic.
+func randomExpression(r *rand.Rand, depth int) Expression {
+	if depth <= 0 || r.Intn(4) == 0 {
+		if r.Intn(3) == 0 {
+			return &Identifier{Name: []string{"x", "y", "z"}[r.Intn(3)]}
+		}
+		return &IntegerLiteral{Value: r.Intn(10)}
+	}
+	if r.Intn(8) == 0 {
+		return &UnaryExpression{Op: SUB, Operand: randomExpression(r, depth-1)}
+	}
+	ops := []Token{ADD, ADD, SUB, SUB, MUL, MUL, DIV, MOD, POW, LT, EQ}
+	return &BinaryExpression{
+		Left:  randomExpression(r, depth-1),
+		Op:    ops[r.Intn(len(ops))],
+		Right: randomExpression(r, depth-1),
+	}
+}
+
+// evaluators are the evaluators that must agree with evaluateExpression.
+var evaluators = map[string]func(Expression, map[string]int) (int, error){
+	"EvaluateIterative": EvaluateIterative,
+	"EvaluateMemo":      EvaluateMemo,
+	"EvaluateContext": func(expr Expression, env map[string]int) (int, error) {
+		return EvaluateContext(context.Background(), expr, env)
+	},
+	"CachedEvaluator": func(expr Expression, env map[string]int) (int, error) {
+		return NewCachedEvaluator(expr, "x").Eval(env)
+	},
+}
+
+// checkAgreement fails t unless eval gives the same result or the same error
+// as evaluateExpression for expr in env.
+func checkAgreement(t *testing.T, name string, eval func(Expression, map[string]int) (int, error), expr Expression, env map[string]int) {
+	t.Helper()
+	want, wantErr := evaluateExpression(expr, env)
+	got, err := eval(expr, env)
+	if fmt.Sprint(err) != fmt.Sprint(wantErr) || got != want {
+		t.Errorf("%s(%s) = %d, %v; evaluateExpression gives %d, %v", name, expr, got, err, want, wantErr)
+	}
+}
+
+func TestEvaluatorsAgree(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	env := map[string]int{"x": 3, "y": -2}
+	for i := 0; i < 2000; i++ {
+		expr := randomExpression(r, 5)
+		for name, eval := range evaluators {
+			checkAgreement(t, name, eval, expr, env)
+		}
+	}
+}
+
+func TestEvaluatorsAgreeOnErrors(t *testing.T) {
+	for _, input := range []string{"2 ^ -1", "1 < 2", "1 / 0", "7 % 0", "1.5 + 1", "\"a\" + 1", "w + 1", "2 * 3 + 4"} {
+		expr, err := Parse(input)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", input, err)
+		}
+		for name, eval := range evaluators {
+			checkAgreement(t, name, eval, expr, nil)
+		}
+	}
+}
+
+func TestEvaluateIterativeDeep(t *testing.T) {
+	var expr Expression = &IntegerLiteral{Value: 0}
+	for i := 0; i < 100000; i++ {
+		expr = &BinaryExpression{Left: expr, Op: ADD, Right: &IntegerLiteral{Value: 1}}
+	}
+	v, err := EvaluateIterative(expr, nil)
+	if err != nil || v != 100000 {
+		t.Errorf("EvaluateIterative = %d, %v, want 100000", v, err)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+// TokenKind is a coarse category of tokens, for callers such as syntax
+// highlighters that do not care about individual operators.
+type TokenKind int
+
+const (
+	KindEOF TokenKind = iota
+	KindIllegal
+	KindIdentifier
+	KindKeyword
+	KindLiteral
+	KindOperator
+	KindDelimiter
+	KindComment
+)
+
+// Kind returns the category t belongs to.
+func (t Token) Kind() TokenKind {
+	switch t {
+	case EOF:
+		return KindEOF
+	case IDENT:
+		return KindIdentifier
+	case DEF, AS, LET:
+		return KindKeyword
+	case INT, FLOAT, CHAR, STRING:
+		return KindLiteral
+	case ADD, SUB, MUL, DIV, MOD, POW, AT, DOTDOT, EQ, NEQ, LT, LE, GT, GE, BANG, PERCENT, ASSIGN:
+		return KindOperator
+	case COMMENT:
+		return KindComment
+	case BAR, LPAREN, RPAREN, LBRACKET, RBRACKET, SEMICOLON, COMMA:
+		return KindDelimiter
+	}
+	return KindIllegal
+}
@@ -0,0 +1,56 @@
+package main
+
+import "testing"
+
+func TestTokenKind(t *testing.T) {
+	want := map[Token]TokenKind{
+		EOF:       KindEOF,
+		ILLEGAL:   KindIllegal,
+		IDENT:     KindIdentifier,
+		INT:       KindLiteral,
+		FLOAT:     KindLiteral,
+		CHAR:      KindLiteral,
+		STRING:    KindLiteral,
+		ADD:       KindOperator,
+		SUB:       KindOperator,
+		MUL:       KindOperator,
+		DIV:       KindOperator,
+		MOD:       KindOperator,
+		POW:       KindOperator,
+		AT:        KindOperator,
+		BAR:       KindDelimiter,
+		DOTDOT:    KindOperator,
+		EQ:        KindOperator,
+		NEQ:       KindOperator,
+		LT:        KindOperator,
+		LE:        KindOperator,
+		GT:        KindOperator,
+		GE:        KindOperator,
+		BANG:      KindOperator,
+		PERCENT:   KindOperator,
+		LPAREN:    KindDelimiter,
+		RPAREN:    KindDelimiter,
+		LBRACKET:  KindDelimiter,
+		RBRACKET:  KindDelimiter,
+		SEMICOLON: KindDelimiter,
+		COMMA:     KindDelimiter,
+		ASSIGN:    KindOperator,
+		DEF:       KindKeyword,
+		AS:        KindKeyword,
+		LET:       KindKeyword,
+		COMMENT:   KindComment,
+	}
+	for tok := EOF; tok <= COMMENT; tok++ {
+		kind, ok := want[tok]
+		if !ok {
+			t.Errorf("no expected kind for %s", TokenName(tok))
+			continue
+		}
+		if got := tok.Kind(); got != kind {
+			t.Errorf("%s.Kind() = %d, want %d", TokenName(tok), got, kind)
+		}
+	}
+	if got := Token(-1).Kind(); got != KindIllegal {
+		t.Errorf("Token(-1).Kind() = %d, want KindIllegal", got)
+	}
+}
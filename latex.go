@@ -0,0 +1,108 @@
+package main
+
+import "fmt"
+
+// ToLaTeX renders expr as LaTeX math. Division becomes \frac, which groups
+// its operands itself; other operands are wrapped in \left(...\right) only
+// where precedence or left-associativity requires it.
+func ToLaTeX(expr Expression) string {
+	switch e := expr.(type) {
+	case *BinaryExpression:
+		if e.Op == DIV {
+			return fmt.Sprintf("\\frac{%s}{%s}", ToLaTeX(e.Left), ToLaTeX(e.Right))
+		}
+		if e.Op == POW {
+			// The braces group the exponent; only the base may need
+			// parentheses.
+			base := ToLaTeX(e.Left)
+			if latexPrecedence(e.Left) <= latexPrecedence(expr) {
+				base = latexGroup(base)
+			}
+			return fmt.Sprintf("%s^{%s}", base, ToLaTeX(e.Right))
+		}
+
+		prec := latexPrecedence(expr)
+		left := ToLaTeX(e.Left)
+		if latexPrecedence(e.Left) < prec {
+			left = latexGroup(left)
+		}
+		right := ToLaTeX(e.Right)
+		if latexPrecedence(e.Right) <= prec {
+			right = latexGroup(right)
+		}
+
+		op, ok := latexOps[e.Op]
+		if !ok {
+			op = e.Symbol()
+		}
+		return fmt.Sprintf("%s %s %s", left, op, right)
+
+	case *UnaryExpression:
+		operand := ToLaTeX(e.Operand)
+		if e.Postfix {
+			// A trailing operator would read as applying to a fraction's
+			// denominator alone, so group every binary operand.
+			if _, ok := e.Operand.(*BinaryExpression); ok {
+				operand = latexGroup(operand)
+			}
+			op, ok := latexOps[e.Op]
+			if !ok {
+				op = e.Symbol()
+			}
+			return operand + op
+		}
+		if latexPrecedence(e.Operand) < latexPrecedence(expr) {
+			operand = latexGroup(operand)
+		}
+		return e.Symbol() + operand
+
+	case *NaryExpression:
+		return ToLaTeX(Unflatten(e))
+
+	case *CallExpression:
+		if e.Function == "abs" && len(e.Arguments) == 1 {
+			return "\\left|" + ToLaTeX(e.Arguments[0]) + "\\right|"
+		}
+		return expr.String()
+
+	default:
+		return expr.String()
+	}
+}
+
+var latexOps = map[Token]string{
+	MUL:     "\\cdot",
+	MOD:     "\\bmod",
+	PERCENT: "\\%",
+	EQ:      "=",
+	NEQ:     "\\neq",
+	LE:      "\\leq",
+	GE:      "\\geq",
+}
+
+// latexPrecedence returns how tightly expr binds once rendered. Fractions and
+// literals are self-delimiting, so they never need grouping.
+func latexPrecedence(expr Expression) int {
+	switch e := expr.(type) {
+	case *BinaryExpression:
+		switch {
+		case isComparisonOp(e.Op):
+			return 0
+		case isAddOp(e.Op):
+			return 1
+		case e.Op == MUL || e.Op == MOD:
+			return 2
+		case e.Op == POW:
+			return 4
+		}
+	case *UnaryExpression:
+		return 3
+	case *NaryExpression:
+		return latexPrecedence(Unflatten(e))
+	}
+	return 5
+}
+
+func latexGroup(s string) string {
+	return "\\left(" + s + "\\right)"
+}
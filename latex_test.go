@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestToLaTeX(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1/2", `\frac{1}{2}`},
+		{"2*3+4", `2 \cdot 3 + 4`},
+		{"(1/2)/(3/x)", `\frac{\frac{1}{2}}{\frac{3}{x}}`},
+		{"(2+3)*4", `\left(2 + 3\right) \cdot 4`},
+		{"10-(3-2)", `10 - \left(3 - 2\right)`},
+		{"10-3-2", `10 - 3 - 2`},
+		{"2^(1+x)", `2^{1 + x}`},
+		{"(1+2)^3", `\left(1 + 2\right)^{3}`},
+	}
+	for _, tt := range tests {
+		if got := ToLaTeX(mustParse(t, tt.input)); got != tt.want {
+			t.Errorf("ToLaTeX(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
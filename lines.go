@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+)
+
+// LineState is what LexLine needs to know about the document before a line.
+// No token spans lines, so the only carried state is where the line starts.
+// A trailing backslash continues a line without producing a token, and a
+// comment ends at the newline even if the comment ends in one, so neither
+// changes how the next line lexes.
+type LineState struct {
+	Line   int // 1-based line number of the line to lex
+	Offset int // byte offset of the line's first character
+}
+
+// StartState is the LineState for the first line of a document.
+var StartState = LineState{Line: 1}
+
+// LexLine tokenizes one line of a document, without its newline, given the
+// state after the preceding line. It returns the line's tokens, positioned
+// within the whole document, and the state for the following line. Editors
+// can keep the state at each line boundary and re-lex only lines that change,
+// continuing until a line's resulting state matches the one stored for it.
+func LexLine(line string, state LineState) ([]TokenInfo, LineState) {
+	l := NewLexer(bufio.NewReader(strings.NewReader(line + "\n")))
+	l.pos = Position{line: state.Line, column: 0, offset: state.Offset}
+
+	toks := l.Tokens()
+	next := LineState{Line: state.Line + 1, Offset: state.Offset + len(line) + 1}
+	return toks, next
+}
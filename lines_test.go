@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lexDocument tokenizes a whole document at once, for comparison with
+// LexLine.
+func lexDocument(doc string) []TokenInfo {
+	return NewLexer(bufio.NewReader(strings.NewReader(doc))).Tokens()
+}
+
+func TestLexLineEdit(t *testing.T) {
+	first, state := LexLine("x = 1 +", StartState)
+	second, _ := LexLine("2 * y", state)
+	want := lexDocument("x = 1 +\n2 * y")
+	if got := append(first, second...); !reflect.DeepEqual(got, want) {
+		t.Fatalf("tokens = %v, want %v", got, want)
+	}
+
+	// Only the second line changes, so only it is re-lexed.
+	edited, _ := LexLine("(3 - y)", state)
+	want = lexDocument("x = 1 +\n(3 - y)")
+	if got := append(first, edited...); !reflect.DeepEqual(got, want) {
+		t.Errorf("tokens after edit = %v, want %v", got, want)
+	}
+}
+
+func TestLexLineState(t *testing.T) {
+	for _, first := range []string{"1 + \\", "# comment \\", "// comment"} {
+		_, state := LexLine(first, StartState)
+		if want := (LineState{Line: 2, Offset: len(first) + 1}); state != want {
+			t.Errorf("state after %q = %+v, want %+v", first, state, want)
+		}
+		got, _ := LexLine("2", state)
+		want := lexDocument(first + "\n2")
+		if !reflect.DeepEqual(got, want[len(want)-1:]) {
+			t.Errorf("second line after %q lexes as %v, want %v", first, got, want[len(want)-1:])
+		}
+	}
+}
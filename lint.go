@@ -0,0 +1,96 @@
+package main
+
+import "fmt"
+
+// Diagnostic is a warning from Lint about a construct that is legal but
+// probably not what was meant.
+type Diagnostic struct {
+	Pos     Position
+	Message string
+}
+
+// String formats d as line:column: message.
+func (d Diagnostic) String() string {
+	return d.Pos.String() + ": " + d.Message
+}
+
+// Lint reports, in source order, operations with no effect such as x * 1 and
+// x + 0, comparisons between literals whose outcome is fixed, and division
+// or modulo by a literal zero.
+func Lint(expr Expression) []Diagnostic {
+	var diags []Diagnostic
+	Inspect(expr, func(n Node) bool {
+		be, ok := n.(*BinaryExpression)
+		if !ok {
+			return true
+		}
+		if (be.Op == DIV || be.Op == MOD) && isZeroLiteral(be.Right) {
+			diags = append(diags, Diagnostic{be.Right.Pos(), fmt.Sprintf("%s by zero in %s", opName(be.Op), be)})
+		} else if kept := redundantOperand(be); kept != nil {
+			diags = append(diags, Diagnostic{be.Position, fmt.Sprintf("redundant operation: %s is the same as %s", be, kept)})
+		} else if isComparisonOp(be.Op) && isLiteral(be.Left) && isLiteral(be.Right) {
+			if v, err := compare(be.Op, literalValue(be.Left), literalValue(be.Right)); err == nil {
+				diags = append(diags, Diagnostic{be.Position, fmt.Sprintf("comparison %s is always %s", be, v)})
+			}
+		}
+		return true
+	})
+	return diags
+}
+
+// redundantOperand returns the operand that be always evaluates to because
+// the other is an identity for its operator, or nil if there is none.
+func redundantOperand(be *BinaryExpression) Expression {
+	switch be.Op {
+	case ADD:
+		if isIntLiteral(be.Right, 0) {
+			return be.Left
+		}
+		if isIntLiteral(be.Left, 0) {
+			return be.Right
+		}
+	case SUB:
+		if isIntLiteral(be.Right, 0) {
+			return be.Left
+		}
+	case MUL:
+		if isIntLiteral(be.Right, 1) {
+			return be.Left
+		}
+		if isIntLiteral(be.Left, 1) {
+			return be.Right
+		}
+	case DIV, POW:
+		if isIntLiteral(be.Right, 1) {
+			return be.Left
+		}
+	}
+	return nil
+}
+
+func opName(op Token) string {
+	if op == MOD {
+		return "modulo"
+	}
+	return "division"
+}
+
+func isLiteral(expr Expression) bool {
+	switch expr.(type) {
+	case *IntegerLiteral, *FloatLiteral, *StringLiteral:
+		return true
+	}
+	return false
+}
+
+func literalValue(expr Expression) Value {
+	switch e := expr.(type) {
+	case *IntegerLiteral:
+		return Value{Kind: IntValue, Int: e.Value}
+	case *FloatLiteral:
+		return Value{Kind: FloatValue, Float: e.Value}
+	case *StringLiteral:
+		return Value{Kind: StringValue, Str: e.Value}
+	}
+	return Value{}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"x * 1 + 0", []string{
+			"1:1: redundant operation: ((x * 1) + 0) is the same as (x * 1)",
+			"1:1: redundant operation: (x * 1) is the same as x",
+		}},
+		{"y + (1 < 2)", []string{"1:6: comparison (1 < 2) is always true"}},
+		{"x / 0 + y % 0", []string{
+			"1:5: division by zero in (x / 0)",
+			"1:13: modulo by zero in (y % 0)",
+		}},
+		{"2 == 3", []string{"1:1: comparison (2 == 3) is always false"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, d := range Lint(mustParse(t, tt.input)) {
+			got = append(got, d.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Lint(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestLintClean(t *testing.T) {
+	for _, input := range []string{"x * 2 + y", "x < 1", "1 / (2 - 2)", "f(x) - 1"} {
+		if diags := Lint(mustParse(t, input)); len(diags) != 0 {
+			t.Errorf("Lint(%q) = %v, want no diagnostics", input, diags)
+		}
+	}
+}
@@ -2,27 +2,68 @@ package main
 
 import (
 	"bufio"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 type Token int
 
 const (
-	EOF = iota
+	EOF Token = iota
 	ILLEGAL
 	IDENT
 	INT
+	FLOAT
+	CHAR
+	STRING
 
 	// Infix ops
 	ADD // +
 	SUB // -
 	MUL // *
 	DIV // /
+	MOD // %
+	POW // ^ or **
+	AT  // @, which means nothing until given a meaning by RegisterBinaryOp
+
+	BAR    // |
+	DOTDOT // ..
+
+	// Comparison ops
+	EQ  // ==
+	NEQ // !=
+	LT  // <
+	LE  // <=
+	GT  // >
+	GE  // >=
+
+	// Postfix ops
+	BANG    // !
+	PERCENT // % with no operand following
+
+	LPAREN    // (
+	RPAREN    // )
+	LBRACKET  // [
+	RBRACKET  // ]
+	SEMICOLON // ;
+	COMMA     // ,
+	ASSIGN    // =
+
+	// Keywords
+	DEF // def
+	AS  // as
+	LET // let
+
+	// Trivia, produced only when Lexer.EmitTrivia is set
+	COMMENT
 )
 
 var tokens = []string{
@@ -30,56 +71,525 @@ var tokens = []string{
 	ILLEGAL: "ILLEGAL",
 	IDENT:   "IDENT",
 	INT:     "INT",
+	FLOAT:   "FLOAT",
+	CHAR:    "CHAR",
+	STRING:  "STRING",
 	ADD:     "+",
 	SUB:     "-",
 	MUL:     "*",
 	DIV:     "/",
+	MOD:     "%",
+	POW:     "^",
+	AT:      "@",
+	BAR:     "|",
+	DOTDOT:  "..",
+	EQ:      "==",
+	NEQ:     "!=",
+	LT:      "<",
+	LE:      "<=",
+	GT:      ">",
+	GE:      ">=",
+	BANG:    "!",
+	PERCENT: "%",
+	LPAREN:  "(",
+	RPAREN:  ")",
+
+	LBRACKET:  "[",
+	RBRACKET:  "]",
+	SEMICOLON: ";",
+	COMMA:     ",",
+	ASSIGN:    "=",
+
+	DEF: "def",
+	AS:  "as",
+	LET: "let",
+
+	COMMENT: "COMMENT",
+}
+
+// TokenName returns the name or symbol of t, such as "IDENT" or "+". Values
+// outside the known range yield "Token(n)".
+func TokenName(t Token) string {
+	if t < 0 || int(t) >= len(tokens) || tokens[t] == "" {
+		return fmt.Sprintf("Token(%d)", int(t))
+	}
+	return tokens[t]
+}
+
+// keywords maps each reserved word to its token. A word listed here can no
+// longer be used as a name.
+var keywords = map[string]Token{
+	"def": DEF,
+	"as":  AS,
+	"let": LET,
+}
+
+// IsKeyword returns the token for s if s is a reserved word.
+func IsKeyword(s string) (Token, bool) {
+	tok, ok := keywords[s]
+	return tok, ok
+}
+
+// isAddOp reports whether tok is an operator at the additive precedence level.
+func isAddOp(tok Token) bool {
+	return tok == ADD || tok == SUB
+}
+
+// isMulOp reports whether tok is an operator at the multiplicative precedence
+// level.
+func isMulOp(tok Token) bool {
+	return tok == MUL || tok == DIV || tok == MOD
+}
+
+// isComparisonOp reports whether tok is an operator at the comparison
+// precedence level.
+func isComparisonOp(tok Token) bool {
+	return tok == EQ || tok == NEQ || tok == LT || tok == LE || tok == GT || tok == GE
 }
 
+// isInfixOp reports whether tok is a binary operator at any precedence level.
+func isInfixOp(tok Token) bool {
+	return isAddOp(tok) || isMulOp(tok) || isComparisonOp(tok) || tok == DOTDOT || tok == POW || isCustomOp(tok)
+}
+
+// Position is a location in the input. line and column are 1-based; offset
+// is the 0-based byte offset into the input.
 type Position struct {
 	line   int
 	column int
+	offset int
+}
+
+// String formats p as line:column, as in compiler diagnostics.
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.line, p.column)
 }
 
+// DefaultCommentPrefixes are the line-comment prefixes a new Lexer accepts.
+var DefaultCommentPrefixes = []string{"#", "//"}
+
+// DefaultMaxLiteralLength is the longest number literal a new Lexer accepts.
+// No int needs more than a few dozen digits, so longer runs are taken to be
+// hostile input.
+const DefaultMaxLiteralLength = 1000
+
 type Lexer struct {
 	pos    Position
 	reader *bufio.Reader
+
+	// runes holds what has been read from reader and may be needed again:
+	// the runes of the current token for backup, or, once Save has been
+	// called, everything read since. cursor indexes the next rune to return.
+	runes  []bufferedRune
+	cursor int
+	saved  bool
+
+	// Logger, if set, is told about every token the lexer produces.
+	Logger Logger
+
+	// OnError, if set, is called with any error from the underlying reader
+	// other than io.EOF, and when MaxTokens is exceeded. The lexer then
+	// behaves as if the input had ended; Err reports the error afterwards.
+	OnError func(error)
+	err     error
+
+	// MaxTokens, if positive, caps the number of tokens the lexer produces,
+	// so that a server can stop reading an oversized formula early.
+	MaxTokens int
+	count     int
+
+	// MaxLiteralLength, if positive, is the most characters a number
+	// literal may have. The lexer stops reading a longer one and ends the
+	// input with an error. NewLexer sets it to DefaultMaxLiteralLength.
+	MaxLiteralLength int
+
+	// TabWidth, if positive, makes a tab advance the column to the next
+	// multiple of TabWidth, as an editor displays it, rather than by one.
+	TabWidth int
+
+	// CommentPrefixes lists the strings that start a comment running to the
+	// end of the line. NewLexer sets it to DefaultCommentPrefixes.
+	CommentPrefixes []string
+
+	// EmitTrivia makes the lexer return comments as COMMENT tokens instead
+	// of skipping them. A comment's literal runs from its prefix to the end
+	// of the line, less any trailing white space. The parser does not accept
+	// COMMENT tokens.
+	EmitTrivia bool
+
+	// end is the position just past the last token returned by Lex.
+	end Position
+
+	// done is set once the lexer has produced EOF, and eof is that token.
+	// From then on Lex returns eof again without reading any further.
+	done bool
+	eof  TokenInfo
+
+	// One token of lookahead for the parser, filled by Peek.
+	peeked bool
+	peek   TokenInfo
+}
+
+// Logger receives debug output from a Lexer. *testing.T satisfies it.
+type Logger interface {
+	Logf(format string, args ...any)
+}
+
+type bufferedRune struct {
+	r   rune
+	pos Position // lexer position before r was read
+}
+
+// Err returns the first error encountered reading the input, if any.
+func (l *Lexer) Err() error {
+	return l.err
+}
+
+// LexerState is a snapshot of a Lexer's progress, taken by Save.
+type LexerState struct {
+	pos    Position
+	cursor int
+	count  int
+	end    Position
+	done   bool
+	peeked bool
+	peek   TokenInfo
+}
+
+// Save returns the lexer's current state so that a parser can try a
+// production and roll back with Restore. After the first Save the lexer keeps
+// every rune it reads, so memory grows with the input consumed.
+func (l *Lexer) Save() LexerState {
+	l.saved = true
+	return LexerState{pos: l.pos, cursor: l.cursor, count: l.count, end: l.end, done: l.done, peeked: l.peeked, peek: l.peek}
+}
+
+// Restore rewinds the lexer to a state returned by Save, so subsequent
+// tokens are lexed again from that point.
+func (l *Lexer) Restore(s LexerState) {
+	l.pos = s.pos
+	l.cursor = s.cursor
+	l.count = s.count
+	l.end = s.end
+	l.done = s.done
+	l.peeked = s.peeked
+	l.peek = s.peek
+}
+
+// TokenInfo is a token together with its literal text and its extent in the
+// input.
+type TokenInfo struct {
+	Token   Token
+	Literal string
+	Pos     Position
+	End     Position
+}
+
+// Lex returns the next token along with the position of its first character.
+// Once it has returned EOF it keeps returning EOF, at the same position,
+// without reading from the input again.
+func (l *Lexer) Lex() (Position, Token, string) {
+	if !l.peeked {
+		l.peek = l.scan()
+	}
+	l.peeked = false
+	l.end = l.peek.End
+	return l.peek.Pos, l.peek.Token, l.peek.Literal
+}
+
+// Next is Lex returning a TokenInfo, which makes *Lexer a Tokenizer.
+func (l *Lexer) Next() TokenInfo {
+	pos, tok, lit := l.Lex()
+	return TokenInfo{Token: tok, Literal: lit, Pos: pos, End: l.end}
+}
+
+// Peek returns the next token without consuming it.
+func (l *Lexer) Peek() (Position, Token, string) {
+	if !l.peeked {
+		l.peek = l.scan()
+		l.peeked = true
+	}
+	return l.peek.Pos, l.peek.Token, l.peek.Literal
+}
+
+// Tokens consumes the rest of the input and returns its tokens, not
+// including the final EOF.
+func (l *Lexer) Tokens() []TokenInfo {
+	return l.AppendTokens(nil)
 }
 
-func (l *Lexer) Lex() (Token, string) {
+// AppendTokens is like Tokens but appends to dst and returns the extended
+// slice, so callers can reuse a backing array across inputs.
+func (l *Lexer) AppendTokens(dst []TokenInfo) []TokenInfo {
 	for {
-		r, _, err := l.reader.ReadRune()
+		pos, tok, lit := l.Lex()
+		if tok == EOF {
+			return dst
+		}
+		dst = append(dst, TokenInfo{Token: tok, Literal: lit, Pos: pos, End: l.end})
+	}
+}
+
+// Tokenize lexes everything r provides. It carries on past ILLEGAL tokens,
+// which are included in its result, and returns an *IllegalTokenError for
+// each of them, joined with errors.Join and followed by the error that
+// stopped reading, if any.
+func Tokenize(r io.Reader) ([]TokenInfo, error) {
+	l := NewLexer(bufio.NewReader(r))
+	toks := l.Tokens()
+	var errs []error
+	for _, tok := range toks {
+		if tok.Token == ILLEGAL {
+			errs = append(errs, &IllegalTokenError{Pos: tok.Pos, Literal: tok.Literal})
+		}
+	}
+	if l.Err() != nil {
+		errs = append(errs, l.Err())
+	}
+	return toks, errors.Join(errs...)
+}
+
+// IllegalTokenError reports input that does not form a token: an unknown
+// character, a malformed number or an unterminated literal.
+type IllegalTokenError struct {
+	Pos     Position
+	Literal string
+}
+
+func (e *IllegalTokenError) Error() string {
+	pos, lit := e.Pos, e.Literal
+	if lit == "" {
+		return fmt.Sprintf("illegal character at %s", pos)
+	}
+	switch lit[0] {
+	case '"':
+		return fmt.Sprintf("unterminated or invalid string literal %s at %s", lit, pos)
+	case '\'':
+		return fmt.Sprintf("unterminated or invalid character literal %s at %s", lit, pos)
+	}
+	if r, _ := utf8.DecodeRuneInString(lit); unicode.IsDigit(r) {
+		return fmt.Sprintf("malformed number %s at %s", lit, pos)
+	}
+	return fmt.Sprintf("illegal character %q at %s", lit, pos)
+}
+
+func (l *Lexer) scan() TokenInfo {
+	if l.done {
+		return l.eof
+	}
+	if !l.saved {
+		// Nothing before the current token can be backed up into.
+		n := copy(l.runes, l.runes[l.cursor:])
+		l.runes = l.runes[:n]
+		l.cursor = 0
+	}
+
+	pos, tok, lit := l.lex()
+	end := l.next()
+	if tok != EOF && l.MaxTokens > 0 {
+		if l.count == l.MaxTokens {
+			l.fail(fmt.Errorf("too many tokens: the limit of %d was reached at %s", l.MaxTokens, pos))
+			tok, lit = EOF, ""
+		} else {
+			l.count++
+		}
+	}
+	if tok == EOF && l.end.line != 0 {
+		// Report EOF just past the last token, so that errors at the end of
+		// input point at the same place whether or not trailing whitespace
+		// or a final newline follows it.
+		pos, end = l.end, l.end
+	}
+	if l.Logger != nil {
+		l.Logger.Logf("%s\t%s\t%q", pos, TokenName(tok), lit)
+	}
+	info := TokenInfo{Token: tok, Literal: lit, Pos: pos, End: end}
+	if tok == EOF {
+		l.done, l.eof = true, info
+	}
+	return info
+}
+
+func (l *Lexer) lex() (Position, Token, string) {
+	for {
+		start := l.next()
+		r, err := l.read()
 		if err != nil {
-			if err == io.EOF {
-				return EOF, ""
+			return start, EOF, ""
+		}
+
+		if start.offset == 0 && r == '\uFEFF' {
+			// Skip a byte-order mark without counting it as a column.
+			l.pos.column = 0
+			continue
+		}
+		if start.offset == 0 && l.matchPrefix(r, "#!") {
+			// A shebang line lets expression files be made executable.
+			l.skipLine()
+			continue
+		}
+		if l.skipComment(r) {
+			if l.EmitTrivia {
+				return start, COMMENT, strings.TrimRightFunc(l.text(start), unicode.IsSpace)
 			}
-			log.Fatal(err)
+			continue
 		}
-		l.pos.column++
 
 		switch r {
 		case '\n':
 			l.resetPosition()
 		case '+':
-			return ADD, "+"
+			return start, ADD, "+"
 		case '-':
-			return SUB, "-"
+			return start, SUB, "-"
 		case '*':
-			return MUL, "*"
+			if l.matchNext('*') {
+				return start, POW, "**"
+			}
+			return start, MUL, "*"
 		case '/':
-			return DIV, "/"
+			return start, DIV, "/"
+		case '%':
+			if l.operandFollows() {
+				return start, MOD, "%"
+			}
+			return start, PERCENT, "%"
+		case '|':
+			return start, BAR, "|"
+		case '^':
+			return start, POW, "^"
+		case '@':
+			return start, AT, "@"
+		case '.':
+			next, err := l.read()
+			if err == nil && next == '.' {
+				return start, DOTDOT, ".."
+			}
+			if err == nil {
+				l.backup()
+			}
+			return start, ILLEGAL, "."
+		case '!':
+			if l.matchNext('=') {
+				return start, NEQ, "!="
+			}
+			return start, BANG, "!"
+		case '<':
+			if l.matchNext('=') {
+				return start, LE, "<="
+			}
+			return start, LT, "<"
+		case '>':
+			if l.matchNext('=') {
+				return start, GE, ">="
+			}
+			return start, GT, ">"
+		case '(':
+			return start, LPAREN, "("
+		case ')':
+			return start, RPAREN, ")"
+		case '[':
+			return start, LBRACKET, "["
+		case ']':
+			return start, RBRACKET, "]"
+		case ';':
+			return start, SEMICOLON, ";"
+		case ',':
+			return start, COMMA, ","
+		case '=':
+			if l.matchNext('=') {
+				return start, EQ, "=="
+			}
+			return start, ASSIGN, "="
+		case '\'':
+			if lit, ok := l.lexChar(); ok {
+				return start, CHAR, lit
+			}
+			return start, ILLEGAL, l.text(start)
+		case '"':
+			if lit, ok := l.lexString(); ok {
+				return start, STRING, lit
+			}
+			return start, ILLEGAL, l.text(start)
+		case '\\':
+			// A backslash before a newline continues the line.
+			next, err := l.read()
+			if err == nil && next == '\n' {
+				l.resetPosition()
+				continue
+			}
+			if err == nil {
+				l.backup()
+			}
+			return start, ILLEGAL, "\\"
 		default:
 			if unicode.IsSpace(r) {
 				continue
 			} else if unicode.IsDigit(r) {
 				l.backup()
-				lit := l.lexInt()
-				return INT, lit
+				tok, lit := l.lexNumber()
+				if l.MaxLiteralLength > 0 && len(lit) > l.MaxLiteralLength {
+					l.fail(fmt.Errorf("number literal longer than %d characters at %s", l.MaxLiteralLength, start))
+					return start, EOF, ""
+				}
+				return start, tok, lit
+			} else if isIdentStart(r) {
+				l.backup()
+				lit := l.lexIdent()
+				if tok, ok := IsKeyword(lit); ok {
+					return start, tok, lit
+				}
+				return start, IDENT, lit
 			} else {
-				return ILLEGAL, string(r)
+				return start, ILLEGAL, string(r)
+			}
+		}
+	}
+}
+
+// fail records err as the reason the input ended early, unless an earlier
+// error already did.
+func (l *Lexer) fail(err error) {
+	if l.err != nil {
+		return
+	}
+	l.err = err
+	if l.OnError != nil {
+		l.OnError(err)
+	}
+}
+
+// next returns the position of the next rune to be read.
+func (l *Lexer) next() Position {
+	return Position{line: l.pos.line, column: l.pos.column + 1, offset: l.pos.offset}
+}
+
+// read returns the next rune. Its only error is io.EOF: a failure from the
+// underlying reader is recorded, reported to OnError, and ends the input.
+func (l *Lexer) read() (rune, error) {
+	if l.cursor == len(l.runes) {
+		if l.err != nil {
+			return 0, io.EOF
+		}
+		r, _, err := l.reader.ReadRune()
+		if err != nil {
+			if err != io.EOF {
+				l.fail(err)
 			}
+			return 0, io.EOF
 		}
+		l.runes = append(l.runes, bufferedRune{r: r, pos: l.pos})
 	}
+
+	br := l.runes[l.cursor]
+	l.cursor++
+	l.pos = br.pos
+	l.pos.column++
+	if br.r == '\t' && l.TabWidth > 0 {
+		l.pos.column = ((l.pos.column-1)/l.TabWidth + 1) * l.TabWidth
+	}
+	l.pos.offset += utf8.RuneLen(br.r)
+	return br.r, nil
 }
 
 func (l *Lexer) resetPosition() {
@@ -88,24 +598,355 @@ func (l *Lexer) resetPosition() {
 }
 
 func (l *Lexer) backup() {
-	if err := l.reader.UnreadRune(); err != nil {
-		log.Fatal(err)
+	l.cursor--
+	l.pos = l.runes[l.cursor].pos
+}
+
+// operandFollows reports whether the next rune other than white space can
+// start an operand, without consuming anything. It decides whether '%' is
+// the modulo operator, as in "10 % 3", or a percentage, as in "50%" or
+// "50% * 200". A sign is not an operand, so "50% - 10" and "50% + 10" both
+// apply the percentage, and a negative divisor needs parentheses:
+// "10 % (-3)". A following '|' is taken as the bar closing an absolute value
+// after a percentage, as in "|50%|".
+func (l *Lexer) operandFollows() bool {
+	n := 0
+	defer func() {
+		for ; n > 0; n-- {
+			l.backup()
+		}
+	}()
+	for {
+		r, err := l.read()
+		if err != nil {
+			return false
+		}
+		n++
+		if !unicode.IsSpace(r) {
+			return unicode.IsDigit(r) || isIdentStart(r) || strings.ContainsRune("([.'\"", r)
+		}
 	}
-	l.pos.column--
 }
 
-func (l *Lexer) lexInt() string {
-	var lit string
+// matchNext consumes the next rune if it is want.
+func (l *Lexer) matchNext(want rune) bool {
+	r, err := l.read()
+	if err != nil {
+		return false
+	}
+	if r != want {
+		l.backup()
+		return false
+	}
+	return true
+}
+
+// skipComment reports whether r begins one of the comment prefixes, in which
+// case the comment is consumed up to, but not including, the newline.
+func (l *Lexer) skipComment(r rune) bool {
+	for _, prefix := range l.CommentPrefixes {
+		if l.matchPrefix(r, prefix) {
+			l.skipLine()
+			return true
+		}
+	}
+	return false
+}
+
+// matchPrefix reports whether r followed by the upcoming input spells prefix.
+// On a mismatch nothing past r is consumed.
+func (l *Lexer) matchPrefix(r rune, prefix string) bool {
+	first, size := utf8.DecodeRuneInString(prefix)
+	if first != r {
+		return false
+	}
+
+	n := 0
+	for _, want := range prefix[size:] {
+		got, err := l.read()
+		if err == nil {
+			n++
+		}
+		if err != nil || got != want {
+			for ; n > 0; n-- {
+				l.backup()
+			}
+			return false
+		}
+	}
+	return true
+}
+
+func (l *Lexer) skipLine() {
 	for {
-		r, _, err := l.reader.ReadRune()
+		r, err := l.read()
+		if err != nil {
+			return
+		}
+		if r == '\n' {
+			l.backup()
+			return
+		}
+	}
+}
+
+// lexChar reads the rest of a character literal after its opening quote and
+// returns its contents with escapes decoded. ok is false if the literal is
+// unterminated or contains an unknown escape. Whether the contents are
+// exactly one character is left to the parser, which can report it better.
+func (l *Lexer) lexChar() (lit string, ok bool) {
+	return l.lexQuoted('\'')
+}
+
+// lexString reads the rest of a string literal after its opening quote, like
+// lexChar.
+func (l *Lexer) lexString() (lit string, ok bool) {
+	return l.lexQuoted('"')
+}
+
+// lexQuoted reads up to and including the closing quote. Quoted literals
+// cannot span lines.
+func (l *Lexer) lexQuoted(quote rune) (lit string, ok bool) {
+	for {
+		r, err := l.read()
+		if err != nil {
+			return lit, false
+		}
+		switch r {
+		case quote:
+			return lit, true
+		case '\n':
+			l.backup()
+			return lit, false
+		case '\\':
+			if r, ok = l.lexEscape(); !ok {
+				return lit, false
+			}
+		}
+		lit = lit + string(r)
+	}
+}
+
+// lexEscape reads the character after a backslash and returns the rune it
+// stands for.
+func (l *Lexer) lexEscape() (rune, bool) {
+	r, err := l.read()
+	if err != nil {
+		return 0, false
+	}
+	switch r {
+	case 'n':
+		return '\n', true
+	case 't':
+		return '\t', true
+	case '\\', '\'', '"':
+		return r, true
+	}
+	return 0, false
+}
+
+// text returns the source text consumed since start, which must lie within
+// the current token.
+func (l *Lexer) text(start Position) string {
+	var lit string
+	for _, br := range l.runes[:l.cursor] {
+		if br.pos.offset >= start.offset {
+			lit = lit + string(br.r)
+		}
+	}
+	return lit
+}
+
+// numberState is a state of the machine lexNumber runs.
+type numberState int
+
+const (
+	numInt        numberState = iota // decimal digits
+	numBase                          // after 0x, 0o or 0b, before any digit
+	numBaseDigits                    // digits after a base prefix
+	numFrac                          // digits after the decimal point
+	numExp                           // after e, before the sign or digits
+	numExpSign                       // after the exponent's sign
+	numExpDigits                     // digits of the exponent
+	numDone                          // the literal has ended
+	numMalformed                     // the literal cannot be completed
+)
+
+// lexNumber reads a number literal, which is one of
+//
+//	123                  INT
+//	0x1f, 0o17, 0b101    INT in base 16, 8 or 2
+//	1.5                  FLOAT
+//	1e9, 1.5e-3          FLOAT
+//
+// Digits may be grouped with single underscores between them, as in
+// 1_000_000 or 0xffff_ffff. A decimal point is only part of the number if a
+// digit follows it, so 1..3 is a range. A literal that has the wrong shape,
+// such as 1.2.3, 1e, 0x or 12abc, is returned whole as ILLEGAL. lexNumber
+// stops early once the literal is over MaxLiteralLength, leaving the rest of
+// it unread.
+func (l *Lexer) lexNumber() (Token, string) {
+	var lit []rune
+	tok, state, base := INT, numInt, 10
+	for state != numDone && state != numMalformed {
+		if l.MaxLiteralLength > 0 && len(lit) > l.MaxLiteralLength {
+			return tok, string(lit)
+		}
+		r, err := l.read()
 		if err != nil {
-			if err == io.EOF {
-				return lit
+			r = 0
+		}
+		if r == '_' && l.isSeparator(state, base, lit) {
+			lit = append(lit, r)
+			continue
+		}
+
+		next := numDone
+		switch state {
+		case numInt:
+			switch {
+			case unicode.IsDigit(r):
+				next = numInt
+			case r == '.' && l.digitFollows():
+				tok, next = FLOAT, numFrac
+			case r == 'e' || r == 'E':
+				tok, next = FLOAT, numExp
+			case len(lit) == 1 && lit[0] == '0' && basePrefix(r) != 0:
+				base, next = basePrefix(r), numBase
+			}
+		case numBase, numBaseDigits:
+			if isBaseDigit(r, base) {
+				next = numBaseDigits
+			} else if state == numBase {
+				next = numMalformed
 			}
-			log.Fatal(err)
+		case numFrac:
+			switch {
+			case unicode.IsDigit(r):
+				next = numFrac
+			case r == 'e' || r == 'E':
+				next = numExp
+			case r == '.' && l.digitFollows():
+				next = numMalformed
+			}
+		case numExp:
+			switch {
+			case r == '+' || r == '-':
+				next = numExpSign
+			case unicode.IsDigit(r):
+				next = numExpDigits
+			default:
+				next = numMalformed
+			}
+		case numExpSign, numExpDigits:
+			if unicode.IsDigit(r) {
+				next = numExpDigits
+			} else if state == numExpSign {
+				next = numMalformed
+			}
+		}
+
+		if next == numDone && (isIdentStart(r) || unicode.IsDigit(r)) {
+			// The number runs straight into a name, or into digits its
+			// base does not allow.
+			next = numMalformed
+		}
+		if next == numDone || next == numMalformed {
+			if err == nil {
+				l.backup()
+			}
+		} else {
+			lit = append(lit, r)
+		}
+		state = next
+	}
+
+	if state == numMalformed {
+		return ILLEGAL, string(lit) + l.skipMalformed()
+	}
+	return tok, string(lit)
+}
+
+// digitFollows reports whether the next rune is a digit, without consuming it.
+func (l *Lexer) digitFollows() bool {
+	return l.baseDigitFollows(10)
+}
+
+func (l *Lexer) baseDigitFollows(base int) bool {
+	r, err := l.read()
+	if err != nil {
+		return false
+	}
+	l.backup()
+	if base == 10 {
+		return unicode.IsDigit(r)
+	}
+	return isBaseDigit(r, base)
+}
+
+// isSeparator reports whether an underscore just read in state separates two
+// digits of lit: one before it and one after it.
+func (l *Lexer) isSeparator(state numberState, base int, lit []rune) bool {
+	switch state {
+	case numInt, numFrac, numExpDigits:
+		base = 10
+	case numBaseDigits:
+	default:
+		return false
+	}
+	last := lit[len(lit)-1]
+	return (unicode.IsDigit(last) || isBaseDigit(last, base)) && l.baseDigitFollows(base)
+}
+
+// skipMalformed consumes the rest of a malformed number: the letters, digits
+// and decimal points that the writer presumably meant to be part of it.
+func (l *Lexer) skipMalformed() string {
+	var rest []rune
+	for {
+		r, err := l.read()
+		if err != nil {
+			return string(rest)
+		}
+		if !isIdentStart(r) && !unicode.IsDigit(r) && !(r == '.' && l.digitFollows()) {
+			l.backup()
+			return string(rest)
 		}
-		l.pos.column++
-		if unicode.IsDigit(r) {
+		rest = append(rest, r)
+	}
+}
+
+// basePrefix returns the base that r selects after a leading 0, or 0 if it
+// selects none.
+func basePrefix(r rune) int {
+	switch r {
+	case 'x', 'X':
+		return 16
+	case 'o', 'O':
+		return 8
+	case 'b', 'B':
+		return 2
+	}
+	return 0
+}
+
+func isBaseDigit(r rune, base int) bool {
+	switch {
+	case '0' <= r && r <= '9':
+		return int(r-'0') < base
+	case 'a' <= r && r <= 'f', 'A' <= r && r <= 'F':
+		return base == 16
+	}
+	return false
+}
+
+func (l *Lexer) lexIdent() string {
+	var lit string
+	for {
+		r, err := l.read()
+		if err != nil {
+			return lit
+		}
+		if isIdentStart(r) || unicode.IsDigit(r) {
 			lit = lit + string(r)
 		} else {
 			l.backup()
@@ -114,15 +955,30 @@ func (l *Lexer) lexInt() string {
 	}
 }
 
-func NewLexer(reader *bufio.Reader) *Lexer {
-	return &Lexer{
-		pos:    Position{line: 1, column: 0},
-		reader: bufio.NewReader(reader),
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// NewLexer returns a lexer reading from reader, with its settings at their
+// defaults and then adjusted by opts.
+func NewLexer(reader *bufio.Reader, opts ...LexerOption) *Lexer {
+	l := &Lexer{
+		pos:              Position{line: 1, column: 0, offset: 0},
+		reader:           bufio.NewReader(reader),
+		CommentPrefixes:  DefaultCommentPrefixes,
+		MaxLiteralLength: DefaultMaxLiteralLength,
+	}
+	for _, opt := range opts {
+		opt(l)
 	}
+	return l
 }
 
 type Node interface {
+	// Pos returns the position of the node's first character.
 	Pos() Position
+	// End returns the position immediately after the node's last character.
+	End() Position
 	String() string
 }
 
@@ -132,133 +988,744 @@ type Expression interface {
 }
 
 type BinaryExpression struct {
-	Left     Expression
-	Op       Token
-	Right    Expression
-	Position Position
+	Left        Expression
+	Op          Token
+	Right       Expression
+	Position    Position
+	EndPosition Position
 }
 
 func (be *BinaryExpression) Pos() Position {
 	return be.Position
 }
 
+func (be *BinaryExpression) End() Position {
+	return be.EndPosition
+}
+
 func (be *BinaryExpression) String() string {
-	return fmt.Sprintf("(%s %s %s)", be.Left.String(), tokens[be.Op], be.Right.String())
+	return fmt.Sprintf("(%s %s %s)", be.Left.String(), be.Symbol(), be.Right.String())
+}
+
+// Symbol returns the operator as written, such as "+". An Op that is not a
+// known token yields "Token(n)", as from TokenName.
+func (be *BinaryExpression) Symbol() string {
+	return TokenName(be.Op)
 }
 
 func (be *BinaryExpression) exprNode() {}
 
-type IntegerLiteral struct {
-	Value    int
-	Position Position
+// UnaryExpression applies a single-operand operator, written before the
+// operand or, if Postfix is set, after it.
+type UnaryExpression struct {
+	Op          Token
+	Operand     Expression
+	Postfix     bool
+	Position    Position
+	EndPosition Position
+}
+
+func (ue *UnaryExpression) Pos() Position {
+	return ue.Position
+}
+
+func (ue *UnaryExpression) End() Position {
+	return ue.EndPosition
 }
 
-// exprNode implements Expression.
-func (*IntegerLiteral) exprNode() {
-	panic("unimplemented")
+func (ue *UnaryExpression) String() string {
+	if ue.Postfix {
+		return fmt.Sprintf("(%s%s)", ue.Operand.String(), ue.Symbol())
+	}
+	return fmt.Sprintf("(%s%s)", ue.Symbol(), ue.Operand.String())
+}
+
+// Symbol returns the operator as written, like BinaryExpression.Symbol.
+func (ue *UnaryExpression) Symbol() string {
+	return TokenName(ue.Op)
 }
 
+func (ue *UnaryExpression) exprNode() {}
+
+type IntegerLiteral struct {
+	Value int
+
+	// Literal, if set, is the literal as written in the source, such as
+	// 1_000 or 0xff, and is what String returns. The parser sets it only
+	// when WithPreserveLiterals is given.
+	Literal string
+
+	Position    Position
+	EndPosition Position
+}
+
+func (*IntegerLiteral) exprNode() {}
+
 func (il *IntegerLiteral) Pos() Position {
 	return il.Position
 }
 
+func (il *IntegerLiteral) End() Position {
+	return il.EndPosition
+}
+
 func (il *IntegerLiteral) String() string {
+	if il.Literal != "" {
+		return il.Literal
+	}
 	return strconv.Itoa(il.Value)
 }
 
-func parseExpression(l *Lexer) Expression {
-	return parseAddSubExpr(l)
+type Identifier struct {
+	Name        string
+	Position    Position
+	EndPosition Position
+}
+
+func (*Identifier) exprNode() {}
+
+func (id *Identifier) Pos() Position {
+	return id.Position
+}
+
+func (id *Identifier) End() Position {
+	return id.EndPosition
+}
+
+func (id *Identifier) String() string {
+	return id.Name
+}
+
+type FloatLiteral struct {
+	Value float64
+
+	// Literal, if set, is the literal as written, like IntegerLiteral's.
+	Literal string
+
+	Position    Position
+	EndPosition Position
+}
+
+func (*FloatLiteral) exprNode() {}
+
+func (fl *FloatLiteral) Pos() Position {
+	return fl.Position
+}
+
+func (fl *FloatLiteral) End() Position {
+	return fl.EndPosition
+}
+
+func (fl *FloatLiteral) String() string {
+	if fl.Literal != "" {
+		return fl.Literal
+	}
+	return formatFloat(fl.Value)
+}
+
+// CastExpression converts its operand to Type, which is "int" or "float".
+type CastExpression struct {
+	Operand     Expression
+	Type        string
+	Position    Position
+	EndPosition Position
+}
+
+func (*CastExpression) exprNode() {}
+
+func (ce *CastExpression) Pos() Position {
+	return ce.Position
+}
+
+func (ce *CastExpression) End() Position {
+	return ce.EndPosition
+}
+
+func (ce *CastExpression) String() string {
+	return fmt.Sprintf("(%s as %s)", ce.Operand.String(), ce.Type)
+}
+
+// RangeExpression is an inclusive integer range such as 1..5.
+type RangeExpression struct {
+	From        Expression
+	To          Expression
+	Position    Position
+	EndPosition Position
+}
+
+func (*RangeExpression) exprNode() {}
+
+func (re *RangeExpression) Pos() Position {
+	return re.Position
+}
+
+func (re *RangeExpression) End() Position {
+	return re.EndPosition
+}
+
+func (re *RangeExpression) String() string {
+	return fmt.Sprintf("(%s..%s)", re.From.String(), re.To.String())
+}
+
+type StringLiteral struct {
+	Value       string
+	Position    Position
+	EndPosition Position
+}
+
+func (*StringLiteral) exprNode() {}
+
+func (sl *StringLiteral) Pos() Position {
+	return sl.Position
+}
+
+func (sl *StringLiteral) End() Position {
+	return sl.EndPosition
+}
+
+// String quotes the value using only the escapes the lexer understands.
+func (sl *StringLiteral) String() string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range sl.Value {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// ExpressionList is a parenthesized, comma-separated list such as (1, 2, 3).
+type ExpressionList struct {
+	Elements    []Expression
+	Position    Position
+	EndPosition Position
+}
+
+func (*ExpressionList) exprNode() {}
+
+func (el *ExpressionList) Pos() Position {
+	return el.Position
+}
+
+func (el *ExpressionList) End() Position {
+	return el.EndPosition
+}
+
+func (el *ExpressionList) String() string {
+	elems := make([]string, len(el.Elements))
+	for i, elem := range el.Elements {
+		elems[i] = elem.String()
+	}
+	return "(" + strings.Join(elems, ", ") + ")"
+}
+
+func parseExpression(p *parser) (Expression, error) {
+	// Minus is also a prefix operator, and a bar opens |x|.
+	pos, tok, lit := p.Peek()
+	if tok == BANG || tok == PERCENT || (isInfixOp(tok) && tok != SUB) {
+		return nil, fmt.Errorf("unexpected operator '%s' at start of expression, expected a value at %s", lit, pos)
+	}
+	if err := p.enter(pos); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	return parseComparisonExpr(p)
 }
 
-func parseAddSubExpr(l *Lexer) Expression {
-	left := parseMulDivExpr(l)
+// parseComparisonExpr parses comparisons, which bind loosest of all.
+func parseComparisonExpr(p *parser) (Expression, error) {
+	start, _, _ := p.Peek()
+	left, err := parseRangeExpr(p)
+	if err != nil {
+		return nil, err
+	}
 
 	for {
-		tok, _ := l.Lex()
-		if tok != ADD && tok != SUB {
-			l.backup()
-			return left
+		_, tok, _ := p.Peek()
+		if !isComparisonOp(tok) && customPrec(tok) != PrecComparison {
+			return left, nil
 		}
+		p.Lex()
+
+		right, err := parseRangeExpr(p)
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpression{Left: left, Op: tok, Right: right, Position: start, EndPosition: p.end}
+	}
+}
+
+// parseRangeExpr parses an optional start..end range, which binds looser than
+// every arithmetic operator and does not chain.
+func parseRangeExpr(p *parser) (Expression, error) {
+	start, _, _ := p.Peek()
+	from, err := parseAddSubExpr(p)
+	if err != nil {
+		return nil, err
+	}
+	if _, tok, _ := p.Peek(); tok != DOTDOT {
+		return from, nil
+	}
+	p.Lex()
 
-		right := parseMulDivExpr(l)
-		left = &BinaryExpression{Left: left, Op: tok, Right: right, Position: left.Pos()}
+	to, err := parseAddSubExpr(p)
+	if err != nil {
+		return nil, err
 	}
+	return &RangeExpression{From: from, To: to, Position: start, EndPosition: p.end}, nil
 }
 
-func parseMulDivExpr(l *Lexer) Expression {
-	left := parsePrimaryExpr(l)
+func parseAddSubExpr(p *parser) (Expression, error) {
+	start, _, _ := p.Peek()
+	left, err := parseMulDivExpr(p)
+	if err != nil {
+		return nil, err
+	}
 
 	for {
-		tok, _ := l.Lex()
-		if tok != MUL && tok != DIV {
-			l.backup()
-			return left
+		_, tok, _ := p.Peek()
+		if !isAddOp(tok) && customPrec(tok) != PrecSum {
+			return left, nil
 		}
+		p.Lex()
 
-		right := parsePrimaryExpr(l)
-		left = &BinaryExpression{Left: left, Op: tok, Right: right, Position: left.Pos()}
+		right, err := parseMulDivExpr(p)
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpression{Left: left, Op: tok, Right: right, Position: start, EndPosition: p.end}
 	}
 }
 
-func parsePrimaryExpr(l *Lexer) Expression {
-	tok, lit := l.Lex()
+func parseMulDivExpr(p *parser) (Expression, error) {
+	start, _, _ := p.Peek()
+	left, err := parseCastExpr(p)
+	if err != nil {
+		return nil, err
+	}
 
-	if tok == INT {
-		value, _ := strconv.Atoi(lit)
-		return &IntegerLiteral{Value: value, Position: Position{line: l.pos.line, column: l.pos.column}}
+	for {
+		_, tok, _ := p.Peek()
+		if !isMulOp(tok) && customPrec(tok) != PrecProduct {
+			return left, nil
+		}
+		p.Lex()
+
+		right, err := parseCastExpr(p)
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpression{Left: left, Op: tok, Right: right, Position: start, EndPosition: p.end}
 	}
+}
 
-	log.Fatalf("Unexpected token: %s", tokens[tok])
-	return nil // unreachable
+// parseCastExpr parses a unary expression followed by any number of
+// "as type" conversions, so -3.9 as int is (-3.9) as int and 2 * 3 as float
+// is 2 * (3 as float).
+func parseCastExpr(p *parser) (Expression, error) {
+	start, _, _ := p.Peek()
+	expr, err := parseUnaryExpr(p)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, tok, _ := p.Peek(); tok != AS {
+			return expr, nil
+		}
+		p.Lex()
+
+		pos, tok, lit := p.Lex()
+		if tok != IDENT || (lit != "int" && lit != "float") {
+			return nil, expected(pos, tok, "int or float after as")
+		}
+		expr = &CastExpression{Operand: expr, Type: lit, Position: start, EndPosition: p.end}
+	}
+}
+
+// parseUnaryExpr parses prefix minus. It binds tighter than * and /, so
+// -2 * 3 is (-2) * 3, and a minus after a binary minus starts its right
+// operand, so 2 - -3 is 2 - (-3). It binds looser than ^, so -2 ^ 2 is
+// -(2 ^ 2), as in mathematical notation.
+func parseUnaryExpr(p *parser) (Expression, error) {
+	pos, tok, _ := p.Peek()
+	if tok != SUB {
+		return parsePowerExpr(p)
+	}
+	p.Lex()
+	if next, tok, _ := p.Peek(); tok == EOF || tok == RPAREN || tok == RBRACKET || tok == COMMA || tok == SEMICOLON {
+		return nil, expected(next, tok, "a value after unary minus")
+	}
+	if err := p.enter(pos); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	operand, err := parseUnaryExpr(p)
+	if err != nil {
+		return nil, err
+	}
+	return &UnaryExpression{Op: SUB, Operand: operand, Position: pos, EndPosition: p.end}, nil
+}
+
+// parsePowerExpr parses ^ (also spelled **), which is right-associative:
+// 2 ^ 3 ^ 2 is 2 ^ (3 ^ 2). The exponent may itself be negated, as in 2 ^ -1.
+func parsePowerExpr(p *parser) (Expression, error) {
+	start, _, _ := p.Peek()
+	base, err := parsePostfixExpr(p)
+	if err != nil {
+		return nil, err
+	}
+	pos, tok, _ := p.Peek()
+	if tok != POW {
+		return base, nil
+	}
+	p.Lex()
+	if err := p.enter(pos); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	exponent, err := parseUnaryExpr(p)
+	if err != nil {
+		return nil, err
+	}
+	return &BinaryExpression{Left: base, Op: POW, Right: exponent, Position: start, EndPosition: p.end}, nil
+}
+
+// parsePostfixExpr parses a primary expression followed by any number of
+// postfix operators, which bind tighter than every infix operator.
+func parsePostfixExpr(p *parser) (Expression, error) {
+	start, _, _ := p.Peek()
+	expr, err := parsePrimaryExpr(p)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		_, tok, _ := p.Peek()
+		if tok != BANG && tok != PERCENT {
+			return expr, nil
+		}
+		p.Lex()
+		expr = &UnaryExpression{Op: tok, Operand: expr, Postfix: true, Position: start, EndPosition: p.end}
+	}
+}
+
+// parseIntLiteral converts the literal of an INT token, which may have a base
+// prefix and digit separators. A plain literal is always decimal, even with a
+// leading zero.
+func parseIntLiteral(lit string) (int, error) {
+	lit = strings.ReplaceAll(lit, "_", "")
+	if len(lit) > 2 && lit[0] == '0' && basePrefix(rune(lit[1])) != 0 {
+		n, err := strconv.ParseInt(lit, 0, strconv.IntSize)
+		return int(n), err
+	}
+	return strconv.Atoi(lit)
 }
 
-func evaluateExpression(expr Expression) (int, error) {
-	switch e := expr.(type) {
-	case *BinaryExpression:
-		left, err := evaluateExpression(e.Left)
+func parsePrimaryExpr(p *parser) (Expression, error) {
+	pos, tok, lit := p.Lex()
+
+	switch tok {
+	case INT:
+		value, err := parseIntLiteral(lit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %s at %s", lit, pos)
+		}
+		il := &IntegerLiteral{Value: value, Position: pos, EndPosition: p.end}
+		if p.preserveLiterals {
+			il.Literal = lit
+		}
+		return il, nil
+	case FLOAT:
+		value, err := strconv.ParseFloat(strings.ReplaceAll(lit, "_", ""), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %s at %s", lit, pos)
+		}
+		fl := &FloatLiteral{Value: value, Position: pos, EndPosition: p.end}
+		if p.preserveLiterals {
+			fl.Literal = lit
+		}
+		return fl, nil
+	case IDENT:
+		if _, next, _ := p.Peek(); next == LPAREN {
+			return parseCall(p, pos, lit)
+		}
+		return &Identifier{Name: lit, Position: pos, EndPosition: p.end}, nil
+	case CHAR:
+		runes := []rune(lit)
+		if len(runes) == 0 {
+			return nil, fmt.Errorf("empty character literal at %s", pos)
+		}
+		if len(runes) > 1 {
+			return nil, fmt.Errorf("character literal %q holds more than one character at %s", lit, pos)
+		}
+		return &IntegerLiteral{Value: int(runes[0]), Position: pos, EndPosition: p.end}, nil
+	case STRING:
+		return &StringLiteral{Value: lit, Position: pos, EndPosition: p.end}, nil
+	case LPAREN:
+		return parseGroupOrList(p, pos)
+	case LBRACKET:
+		expr, err := parseExpression(p)
 		if err != nil {
-			return 0, err
+			return nil, err
 		}
+		if err := expect(p, RBRACKET); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	case BAR:
+		return parseAbs(p, pos)
+	}
+
+	if isInfixOp(tok) || tok == BANG || tok == PERCENT {
+		// Typically a doubled operator, as in 1 + * 2.
+		return nil, fmt.Errorf("unexpected operator '%s', expected a value at %s", lit, pos)
+	}
+	return nil, unexpected(pos, tok, lit)
+}
 
-		right, err := evaluateExpression(e.Right)
+// parseAbs parses |expr| after the opening bar at pos, as a call to abs. A
+// bar where an operand is expected opens a new pair and a bar where an
+// operator is expected closes the innermost one, so ||x|-1| is abs(abs(x)-1).
+func parseAbs(p *parser, pos Position) (Expression, error) {
+	expr, err := parseExpression(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := expect(p, BAR); err != nil {
+		return nil, err
+	}
+	return &CallExpression{Function: "abs", Arguments: []Expression{expr}, Position: pos, EndPosition: p.end}, nil
+}
+
+// parseGroupOrList parses what follows an opening parenthesis at pos. A
+// single expression is a grouping and is returned as-is; "()" or any comma
+// makes an ExpressionList.
+func parseGroupOrList(p *parser, pos Position) (Expression, error) {
+	if _, tok, _ := p.Peek(); tok == RPAREN {
+		p.Lex()
+		return &ExpressionList{Position: pos, EndPosition: p.end}, nil
+	}
+
+	expr, err := parseExpression(p)
+	if err != nil {
+		return nil, err
+	}
+	if _, tok, _ := p.Peek(); tok != COMMA {
+		if err := expect(p, RPAREN); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+
+	list := &ExpressionList{Elements: []Expression{expr}, Position: pos}
+	for {
+		if _, tok, _ := p.Peek(); tok != COMMA {
+			break
+		}
+		p.Lex()
+
+		expr, err := parseExpression(p)
 		if err != nil {
-			return 0, err
-		}
-
-		switch e.Op {
-		case ADD:
-			return left + right, nil
-		case SUB:
-			return left - right, nil
-		case MUL:
-			return left * right, nil
-		case DIV:
-			if right == 0 {
-				return 0, fmt.Errorf("division by zero")
-			}
-			return left / right, nil
-		default:
-			return 0, fmt.Errorf("unknown operator")
+			return nil, err
 		}
+		list.Elements = append(list.Elements, expr)
+	}
+	if err := expect(p, RPAREN); err != nil {
+		return nil, err
+	}
+	list.EndPosition = p.end
+	return list, nil
+}
+
+// expect consumes the next token, failing unless it is want.
+func expect(p *parser, want Token) error {
+	pos, tok, _ := p.Lex()
+	if tok != want {
+		if isCloser(tok) && isCloser(want) {
+			return fmt.Errorf("mismatched %s at %s, expected %s", TokenName(tok), pos, TokenName(want))
+		}
+		return expected(pos, tok, TokenName(want))
+	}
+	return nil
+}
+
+// ErrUnexpectedEOF is wrapped by parse errors caused by the input ending in
+// the middle of an expression, so that a caller reading input a line at a
+// time can tell that more input would help.
+var ErrUnexpectedEOF = errors.New("unexpected end of input")
 
-	case *IntegerLiteral:
-		return e.Value, nil
+// expected reports that what was wanted where tok was found.
+func expected(pos Position, tok Token, what string) error {
+	if tok == EOF {
+		return fmt.Errorf("%w at %s, expected %s", ErrUnexpectedEOF, pos, what)
+	}
+	return fmt.Errorf("expected %s at %s", what, pos)
+}
+
+func isCloser(tok Token) bool {
+	return tok == RPAREN || tok == RBRACKET || tok == BAR
+}
 
+func unexpected(pos Position, tok Token, lit string) error {
+	switch tok {
+	case EOF:
+		return fmt.Errorf("%w at %s", ErrUnexpectedEOF, pos)
+	case ILLEGAL:
+		return &IllegalTokenError{Pos: pos, Literal: lit}
+	}
+	return fmt.Errorf("unexpected token %s at %s", TokenName(tok), pos)
+}
+
+// Parse parses input as a single expression, failing if anything follows it.
+func Parse(input string, opts ...ParseOption) (Expression, error) {
+	l := NewLexer(bufio.NewReader(strings.NewReader(input)))
+	expr, err := ParseTokens(l, opts...)
+	if l.Err() != nil {
+		// The lexer ended the input early, which explains any parse error.
+		return nil, l.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+// ParseExpr is Parse under a name that says what it accepts: one expression,
+// with anything after it, even a semicolon, an error. ParseStatements parses
+// programs.
+func ParseExpr(input string, opts ...ParseOption) (Expression, error) {
+	return Parse(input, opts...)
+}
+
+// evaluateExpression evaluates expr, resolving identifiers in env. It is the
+// integer-only view of EvaluateValue.
+func evaluateExpression(expr Expression, env map[string]int) (int, error) {
+	v, err := EvaluateValue(expr, env)
+	if err != nil {
+		return 0, err
+	}
+	return v.integer()
+}
+
+// applyOp applies the binary operator op to already-evaluated operands.
+func applyOp(op Token, left, right int) (int, error) {
+	switch op {
+	case ADD:
+		return left + right, nil
+	case SUB:
+		return left - right, nil
+	case MUL:
+		return left * right, nil
+	case DIV:
+		if right == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return left / right, nil
+	case MOD:
+		if right == 0 {
+			return 0, fmt.Errorf("modulo by zero")
+		}
+		return left % right, nil
+	case POW:
+		if right < 0 {
+			return 0, fmt.Errorf("negative exponent %d", right)
+		}
+		// Square and multiply; like the other operators, it wraps on
+		// overflow.
+		result := 1
+		for ; right > 0; right >>= 1 {
+			if right&1 == 1 {
+				result *= left
+			}
+			left *= left
+		}
+		return result, nil
 	default:
-		return 0, fmt.Errorf("unknown expression type")
+		if custom, ok := binaryOps[op]; ok {
+			v, err := custom.fn(Value{Kind: IntValue, Int: left}, Value{Kind: IntValue, Int: right})
+			if err != nil {
+				return 0, err
+			}
+			return v.integer()
+		}
+		return 0, fmt.Errorf("unknown operator")
 	}
 }
 
 func main() {
-	r := bufio.NewReader(os.Stdin)
-	l := NewLexer(r)
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run is the body of main: it reads a program from in, writes its parsed
+// form and value to out, and handles the debugging flags in args.
+func run(args []string, in io.Reader, out io.Writer) error {
+	flags := flag.NewFlagSet("lexer", flag.ContinueOnError)
+	flags.SetOutput(out)
+	dumpTokens := flags.Bool("tokens", false, "print the token stream instead of evaluating")
+	dumpAST := flags.Bool("ast", false, "print the parsed tree instead of evaluating")
+	interactive := flags.Bool("i", false, "read and evaluate one line at a time")
+	sci := flags.Bool("sci", false, "print a float result in scientific notation")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
 
-	expr := parseExpression(l)
-	fmt.Println(expr)
-	result, err := evaluateExpression(expr)
+	if *interactive {
+		return repl(in, out)
+	}
+
+	if *dumpTokens {
+		l := NewLexer(bufio.NewReader(in))
+		l.EmitTrivia = true
+		toks := l.Tokens()
+		if err := l.Err(); err != nil {
+			return err
+		}
+		for _, t := range toks {
+			fmt.Fprintf(out, "%s\t%s\t%s\n", t.Pos, TokenName(t.Token), t.Literal)
+		}
+		return nil
+	}
+
+	l := NewLexer(bufio.NewReader(in))
+	program, err := parseProgram(newParser(l))
+	if l.Err() != nil {
+		// Whatever cut the input short explains the parse error, if any.
+		err = l.Err()
+	}
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	if *dumpAST {
+		fmt.Fprint(out, FormatTree(program))
+		return nil
 	}
 
-	fmt.Println(result)
+	fmt.Fprintln(out, program)
+	result, err := EvaluateProgram(program, nil)
+	if err != nil {
+		return err
+	}
+
+	if *sci && result.Kind == FloatValue {
+		fmt.Fprintln(out, formatScientific(result.Float))
+		return nil
+	}
+	fmt.Fprintln(out, result)
+	return nil
 }
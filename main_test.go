@@ -0,0 +1,915 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newStringLexer(input string, opts ...LexerOption) *Lexer {
+	return NewLexer(bufio.NewReader(strings.NewReader(input)), opts...)
+}
+
+func TestPositionString(t *testing.T) {
+	tests := []struct {
+		pos  Position
+		want string
+	}{
+		{Position{line: 1, column: 1}, "1:1"},
+		{Position{line: 12, column: 34, offset: 200}, "12:34"},
+	}
+	for _, tt := range tests {
+		if got := tt.pos.String(); got != tt.want {
+			t.Errorf("%#v.String() = %q, want %q", tt.pos, got, tt.want)
+		}
+	}
+}
+
+func TestPositionedErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1 + * 2", "unexpected operator '*', expected a value at 1:5"},
+		{"1 +\n  $", "illegal character \"$\" at 2:3"},
+		{"(1 + 2]", "mismatched ] at 1:7, expected )"},
+		{"1 2", "unexpected token INT at 1:3"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.input)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+// checkParseErrors fails t unless each input fails to parse with its
+// expected message.
+func checkParseErrors(t *testing.T, tests []struct{ input, want string }) {
+	t.Helper()
+	for _, tt := range tests {
+		if _, err := Parse(tt.input); err == nil || err.Error() != tt.want {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestConsecutiveOperators(t *testing.T) {
+	checkParseErrors(t, []struct{ input, want string }{
+		{"1 + * 2", "unexpected operator '*', expected a value at 1:5"},
+		{"3 */ 4", "unexpected operator '/', expected a value at 1:4"},
+		{"1 * / 2", "unexpected operator '/', expected a value at 1:5"},
+		{"(2 - * 3)", "unexpected operator '*', expected a value at 1:6"},
+	})
+}
+
+func TestDoubleStarPower(t *testing.T) {
+	checkResults(t, []struct{ input, want string }{
+		{"2**3", "8"},
+		{"2 ** 3 ** 2", "512"},
+		{"2**3 == 2^3", "true"},
+	})
+	if got := lexed("2**3"); got != "2 ** 3" {
+		t.Errorf("lexed(2**3) = %q, want a single ** token", got)
+	}
+	checkParseErrors(t, []struct{ input, want string }{
+		{"2 * *3", "unexpected operator '*', expected a value at 1:5"},
+		{"2* *3", "unexpected operator '*', expected a value at 1:4"},
+	})
+}
+
+func TestLoneMinus(t *testing.T) {
+	checkParseErrors(t, []struct{ input, want string }{
+		{"-", "unexpected end of input at 1:2, expected a value after unary minus"},
+		{"- ", "unexpected end of input at 1:2, expected a value after unary minus"},
+		{"-\n", "unexpected end of input at 1:2, expected a value after unary minus"},
+		{"1 + -", "unexpected end of input at 1:6, expected a value after unary minus"},
+	})
+	if _, err := Parse("-"); !errors.Is(err, ErrUnexpectedEOF) {
+		t.Errorf("Parse(-) error = %v, want ErrUnexpectedEOF", err)
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"50%", "0.5"},
+		{"200 * 50%", "100.0"},
+		{"50% * 200 == 100", "true"},
+		{"10 % 3", "1"},
+		{"10 % (-3)", "1"},
+		{"-10 % (-3)", "-1"},
+		{"10 % (-(1 + 2))", "1"},
+		{"50% - 10", "-9.5"},
+		{"50% + 10", "10.5"},
+		{"50%-10", "-9.5"},
+		{"50%+10", "10.5"},
+		{"(50%) - 10", "-9.5"},
+		{"10 % -3", "-2.9"},
+		{"10 % +3", "3.1"},
+		{"|50%|", "0.5"},
+	}
+	for _, tt := range tests {
+		v, err := EvalValue(tt.input)
+		if err != nil {
+			t.Fatalf("EvalValue(%q): %v", tt.input, err)
+		}
+		if got := v.String(); got != tt.want {
+			t.Errorf("EvalValue(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestPercentOrModulo(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"50% - 10", "((50%) - 10)"},
+		{"50% + 10", "((50%) + 10)"},
+		{"10 % 3", "(10 % 3)"},
+		{"10 % (-3)", "(10 % (-3))"},
+		{"10 % x", "(10 % x)"},
+		{"10 % -x", "((10%) - x)"},
+	}
+	for _, tt := range tests {
+		if got := mustParse(t, tt.input).String(); got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestUnaryMinus(t *testing.T) {
+	tests := []struct {
+		input string
+		tree  string
+		want  string
+	}{
+		{"-2*3", "((-2) * 3)", "-6"},
+		{"2--3", "(2 - (-3))", "5"},
+		{"2 - -3", "(2 - (-3))", "5"},
+		{"-2^2", "(-(2 ^ 2))", "-4"},
+		{"(-2)^2", "((-2) ^ 2)", "4"},
+		{"2 ^ -1 * 4", "((2 ^ (-1)) * 4)", "2.0"},
+		{"--2", "(-(-2))", "2"},
+		{"6 / -2", "(6 / (-2))", "-3"},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.input)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.input, err)
+			continue
+		}
+		if got := expr.String(); got != tt.tree {
+			t.Errorf("Parse(%q) = %s, want %s", tt.input, got, tt.tree)
+		}
+		v, err := EvalValue(tt.input)
+		if err != nil || v.String() != tt.want {
+			t.Errorf("EvalValue(%q) = %s, %v, want %s", tt.input, v, err, tt.want)
+		}
+	}
+}
+
+func TestAppendTokensMatchesTokens(t *testing.T) {
+	inputs := []string{"1 + 2 * (x - 3)", "f(1, 2); y = 'a'", ""}
+	var buf []TokenInfo
+	for _, input := range inputs {
+		want := newStringLexer(input).Tokens()
+		buf = newStringLexer(input).AppendTokens(buf[:0])
+		if len(want) == 0 && len(buf) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(buf, want) {
+			t.Errorf("AppendTokens(%q) = %v, want %v", input, buf, want)
+		}
+	}
+
+	prefix := []TokenInfo{{Token: ILLEGAL, Literal: "?"}}
+	got := newStringLexer("1").AppendTokens(prefix)
+	if len(got) != 2 || got[0] != prefix[0] || got[1].Token != INT {
+		t.Errorf("AppendTokens did not append to dst: %v", got)
+	}
+}
+
+const benchmarkInput = "x * (y + 42) - 7 / z % 3 + f(1, 2, 3) * -x ^ 2"
+
+func BenchmarkTokens(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		newStringLexer(benchmarkInput).Tokens()
+	}
+}
+
+func BenchmarkAppendTokens(b *testing.B) {
+	b.ReportAllocs()
+	var buf []TokenInfo
+	for i := 0; i < b.N; i++ {
+		buf = newStringLexer(benchmarkInput).AppendTokens(buf[:0])
+	}
+}
+
+func TestLexPastEOF(t *testing.T) {
+	for _, input := range []string{"", "1 +", "x  \n", "12"} {
+		l := newStringLexer(input)
+		l.Tokens()
+		first, tok, lit := l.Lex()
+		for i := 0; i < 5; i++ {
+			pos, tok2, lit2 := l.Lex()
+			if tok != EOF || tok2 != EOF || lit != "" || lit2 != "" || pos != first {
+				t.Errorf("Lex past the end of %q = %s %q at %s, want EOF at %s", input, TokenName(tok2), lit2, pos, first)
+			}
+		}
+		if l.Err() != nil {
+			t.Errorf("Err() after %q = %v", input, l.Err())
+		}
+	}
+}
+
+func TestSaveRestore(t *testing.T) {
+	l := newStringLexer("1 + (x * 2) - 3")
+	l.Lex()
+	state := l.Save()
+
+	var first []TokenInfo
+	for i := 0; i < 5; i++ {
+		first = append(first, l.Next())
+	}
+	l.Restore(state)
+	for i, want := range first {
+		if got := l.Next(); got != want {
+			t.Errorf("token %d after Restore = %v, want %v", i, got, want)
+		}
+	}
+	if rest := l.Tokens(); len(rest) != 3 || rest[2].Literal != "3" {
+		t.Errorf("tokens after the replay = %v, want ), - and 3", rest)
+	}
+}
+
+func TestRestoreAfterPeek(t *testing.T) {
+	l := newStringLexer("a b")
+	l.Peek()
+	state := l.Save()
+	l.Lex()
+	l.Lex()
+	l.Restore(state)
+	if _, tok, lit := l.Lex(); tok != IDENT || lit != "a" {
+		t.Errorf("Lex after Restore = %s %q, want IDENT \"a\"", TokenName(tok), lit)
+	}
+}
+
+// lexed returns the literals of the tokens in input, separated by spaces, with
+// those of ILLEGAL tokens marked with a leading '!'.
+func lexed(input string, opts ...LexerOption) string {
+	var lits []string
+	for _, tok := range newStringLexer(input, opts...).Tokens() {
+		if tok.Token == ILLEGAL {
+			tok.Literal = "!" + tok.Literal
+		}
+		lits = append(lits, tok.Literal)
+	}
+	return strings.Join(lits, " ")
+}
+
+func TestMaxLiteralLength(t *testing.T) {
+	l := newStringLexer("1 + " + strings.Repeat("9", DefaultMaxLiteralLength+1) + " + 2")
+	if toks := l.Tokens(); len(toks) != 2 {
+		t.Errorf("tokens = %v, want those before the literal", toks)
+	}
+	want := fmt.Sprintf("number literal longer than %d characters at 1:5", DefaultMaxLiteralLength)
+	if err := l.Err(); err == nil || err.Error() != want {
+		t.Errorf("Err() = %v, want %q", err, want)
+	}
+
+	l = newStringLexer("12345 + 1")
+	l.MaxLiteralLength = 4
+	l.Tokens()
+	if want := "number literal longer than 4 characters at 1:1"; l.Err() == nil || l.Err().Error() != want {
+		t.Errorf("Err() = %v, want %q", l.Err(), want)
+	}
+
+	l = newStringLexer(strings.Repeat("0", DefaultMaxLiteralLength+1))
+	l.MaxLiteralLength = 0
+	if toks := l.Tokens(); len(toks) != 1 || l.Err() != nil {
+		t.Errorf("unlimited lexer = %v, %v, want one literal", toks, l.Err())
+	}
+}
+
+func TestNumberForms(t *testing.T) {
+	valid := []struct {
+		input string
+		tok   Token
+		want  string
+	}{
+		{"42", INT, "42"},
+		{"1_000", INT, "1000"},
+		{"0x1F", INT, "31"},
+		{"0b101", INT, "5"},
+		{"0o17", INT, "15"},
+		{"1.5", FLOAT, "1.5"},
+		{"1e3", FLOAT, "1000.0"},
+		{"1.5e-3", FLOAT, "0.0015"},
+		{"2E+2", FLOAT, "200.0"},
+	}
+	for _, tt := range valid {
+		toks := newStringLexer(tt.input).Tokens()
+		if len(toks) != 1 || toks[0].Token != tt.tok || toks[0].Literal != tt.input {
+			t.Errorf("tokens of %q = %v, want one %s", tt.input, toks, TokenName(tt.tok))
+		}
+		if v, err := EvalValue(tt.input); err != nil || v.String() != tt.want {
+			t.Errorf("EvalValue(%q) = %s, %v, want %s", tt.input, v, err, tt.want)
+		}
+	}
+
+	for _, input := range []string{"1.2.3", "1e", "1e+", "0x", "0b2", "0xg", "1__0", "1_", "1.5x"} {
+		if got := lexed(input); got != "!"+input {
+			t.Errorf("lexed(%q) = %q, want a single ILLEGAL token", input, got)
+		}
+		_, err := Parse(input)
+		if want := "malformed number " + input + " at 1:1"; err == nil || err.Error() != want {
+			t.Errorf("Parse(%q) error = %v, want %q", input, err, want)
+		}
+	}
+}
+
+func TestPreserveLiterals(t *testing.T) {
+	tests := []struct {
+		input     string
+		preserved string
+		canonical string
+	}{
+		{"1_000_000", "1_000_000", "1000000"},
+		{"0xff + 1_0", "(0xff + 1_0)", "(255 + 10)"},
+		{"1_000.5e1", "1_000.5e1", "10005.0"},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.input, WithPreserveLiterals(true))
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.input, err)
+		}
+		if got := expr.String(); got != tt.preserved {
+			t.Errorf("preserved String of %q = %s, want %s", tt.input, got, tt.preserved)
+		}
+		if got := mustParse(t, tt.input).String(); got != tt.canonical {
+			t.Errorf("String of %q = %s, want %s", tt.input, got, tt.canonical)
+		}
+	}
+	expr, err := Parse("1_000_000", WithPreserveLiterals(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if il, ok := expr.(*IntegerLiteral); !ok || il.Value != 1000000 {
+		t.Errorf("Parse(1_000_000) = %#v, want the value 1000000", expr)
+	}
+}
+
+func TestLineComments(t *testing.T) {
+	tests := []struct {
+		input string
+		opts  []LexerOption
+		want  string
+	}{
+		{"1 + 2 # three\n* 4", nil, "1 + 2 * 4"},
+		{"1 // one\n+ 2", nil, "1 + 2"},
+		{"# only a comment", nil, ""},
+		{"1 -- 2", []LexerOption{WithCommentPrefix("--")}, "1"},
+		{"1 -- 2\n+ 3", []LexerOption{WithCommentPrefix("--")}, "1 + 3"},
+		{"1 - 2", []LexerOption{WithCommentPrefix("--")}, "1 - 2"},
+		{"1 # 2", []LexerOption{WithCommentPrefix("--")}, "1 !# 2"},
+		{"1 -- 2", nil, "1 - - 2"},
+	}
+	for _, tt := range tests {
+		if got := lexed(tt.input, tt.opts...); got != tt.want {
+			t.Errorf("lexing %q gives %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestShebang(t *testing.T) {
+	if v, err := Eval("#!/usr/bin/env lexer\n1+2"); err != nil || v != 3 {
+		t.Errorf("Eval with a shebang = %d, %v, want 3", v, err)
+	}
+	// Only a shebang at the very start of the input is skipped. Elsewhere #!
+	// lexes like any other input, which is illegal once # is not a comment
+	// prefix.
+	if got := lexed("1 #!x\n2", WithCommentPrefix("//")); got != "1 !# ! x 2" {
+		t.Errorf("lexing a later #! gives %q", got)
+	}
+	if got := lexed(" #!x\n2", WithCommentPrefix("//")); got != "!# ! x 2" {
+		t.Errorf("lexing an indented #! gives %q", got)
+	}
+}
+
+func TestByteOrderMark(t *testing.T) {
+	if v, err := Eval("\uFEFF1+2"); err != nil || v != 3 {
+		t.Errorf("Eval with a BOM = %d, %v, want 3", v, err)
+	}
+	toks := newStringLexer("\uFEFF1").Tokens()
+	if len(toks) != 1 || toks[0].Pos != (Position{line: 1, column: 1, offset: 3}) {
+		t.Errorf("tokens after a BOM = %v, want 1 at 1:1", toks)
+	}
+	if got := lexed("1\uFEFF"); got != "1 !\uFEFF" {
+		t.Errorf("lexing a later BOM gives %q, want it ILLEGAL", got)
+	}
+}
+
+func TestLineContinuation(t *testing.T) {
+	if v, err := Eval("1 +\\\n2"); err != nil || v != 3 {
+		t.Errorf("Eval of a continued line = %d, %v, want 3", v, err)
+	}
+	toks := newStringLexer("1 +\\\n2").Tokens()
+	if got := len(toks); got != 3 {
+		t.Fatalf("got %d tokens, want 3: %v", got, toks)
+	}
+	if pos := toks[2].Pos; pos.line != 2 || pos.column != 1 {
+		t.Errorf("token after the continuation at %s, want 2:1", pos)
+	}
+	if got := lexed("1 \\ 2"); got != "1 !\\ 2" {
+		t.Errorf("lexing a stray backslash gives %q", got)
+	}
+	if got := lexed("1 \\"); got != "1 !\\" {
+		t.Errorf("lexing a backslash at EOF gives %q", got)
+	}
+}
+
+func TestCharLiterals(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"'A'", 65},
+		{"'A' + 1", 66},
+		{"'\\n'", 10},
+		{"'\\\\'", '\\'},
+		{"'\\''", '\''},
+		{"'é'", 'é'},
+	}
+	for _, tt := range tests {
+		if v, err := Eval(tt.input); err != nil || v != tt.want {
+			t.Errorf("Eval(%q) = %d, %v, want %d", tt.input, v, err, tt.want)
+		}
+	}
+}
+
+func TestCharLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"''", "empty character literal at 1:1"},
+		{"'ab'", "character literal \"ab\" holds more than one character at 1:1"},
+		{"'a", "unterminated or invalid character literal 'a at 1:1"},
+		{"'\\q'", "unterminated or invalid character literal '\\q at 1:1"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.input)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`"hello"`, "hello"},
+		{`""`, ""},
+		{`"say \"hi\"\n"`, "say \"hi\"\n"},
+		{`"a\\b\tc"`, "a\\b\tc"},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.input)
+		if err != nil {
+			t.Fatalf("Parse(%s): %v", tt.input, err)
+		}
+		sl, ok := expr.(*StringLiteral)
+		if !ok || sl.Value != tt.want {
+			t.Errorf("Parse(%s) = %#v, want a StringLiteral %q", tt.input, expr, tt.want)
+		}
+	}
+}
+
+func TestUnterminatedString(t *testing.T) {
+	for _, input := range []string{`"abc`, `"abc\"`, `1 + "`} {
+		_, err := Parse(input)
+		if err == nil || !strings.Contains(err.Error(), "unterminated or invalid string literal") {
+			t.Errorf("Parse(%s) error = %v, want an unterminated string", input, err)
+		}
+	}
+}
+
+func TestExpressionLists(t *testing.T) {
+	list, ok := mustParse(t, "(1, 2, 3)").(*ExpressionList)
+	if !ok || len(list.Elements) != 3 {
+		t.Fatalf("(1, 2, 3) parsed as %#v, want a list of 3", list)
+	}
+	if got, want := list.String(), "(1, 2, 3)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	if _, ok := mustParse(t, "(1+2)").(*BinaryExpression); !ok {
+		t.Errorf("(1+2) did not parse as a grouped scalar")
+	}
+	if list, ok := mustParse(t, "(1+2, x)").(*ExpressionList); !ok || list.Elements[0].String() != "(1 + 2)" {
+		t.Errorf("(1+2, x) parsed as %#v, want a list starting with 1+2", list)
+	}
+
+	v, err := EvalValue("(1, 2 * 3)")
+	if err != nil || v.String() != "(1, 6)" {
+		t.Errorf("EvalValue((1, 2 * 3)) = %s, %v, want (1, 6)", v, err)
+	}
+}
+
+type recordingLogger struct {
+	lines []string
+}
+
+func (r *recordingLogger) Logf(format string, args ...any) {
+	r.lines = append(r.lines, fmt.Sprintf(format, args...))
+}
+
+func TestLogger(t *testing.T) {
+	logger := &recordingLogger{}
+	l := newStringLexer("1 + x")
+	l.Logger = logger
+	l.Tokens()
+	l.Lex() // EOF again, from the saved token, is not logged twice
+
+	want := []string{"1:1\tINT\t\"1\"", "1:3\t+\t\"+\"", "1:5\tIDENT\t\"x\"", "1:6\tEOF\t\"\""}
+	if !reflect.DeepEqual(logger.lines, want) {
+		t.Errorf("logged %q, want %q", logger.lines, want)
+	}
+}
+
+// failingReader returns its data and then err.
+type failingReader struct {
+	data string
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestOnError(t *testing.T) {
+	readErr := errors.New("disk on fire")
+	l := NewLexer(bufio.NewReader(&failingReader{data: "1 + 2", err: readErr}))
+	var got []error
+	l.OnError = func(err error) { got = append(got, err) }
+
+	toks := l.Tokens()
+	if len(got) != 1 || got[0] != readErr {
+		t.Errorf("OnError got %v, want [%v]", got, readErr)
+	}
+	if !errors.Is(l.Err(), readErr) {
+		t.Errorf("Err() = %v, want %v", l.Err(), readErr)
+	}
+	if len(toks) != 3 {
+		t.Errorf("tokens = %v, want those read before the failure", toks)
+	}
+	if _, tok, _ := l.Lex(); tok != EOF {
+		t.Errorf("Lex after the failure = %s, want EOF", TokenName(tok))
+	}
+}
+
+func TestTokenizeJoinsErrors(t *testing.T) {
+	toks, err := Tokenize(strings.NewReader("1 $ 2\n  ? 3"))
+	if err == nil {
+		t.Fatal("Tokenize succeeded with two illegal characters")
+	}
+	if len(toks) != 5 {
+		t.Errorf("tokens = %v, want all 5, illegal ones included", toks)
+	}
+	want := "illegal character \"$\" at 1:3\nillegal character \"?\" at 2:3"
+	if err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+
+	var first *IllegalTokenError
+	if !errors.As(err, &first) || first.Pos.String() != "1:3" || first.Literal != "$" {
+		t.Errorf("errors.As = %v, want the error for $", first)
+	}
+	var got []string
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var ite *IllegalTokenError
+		if errors.As(err, &ite) {
+			got = append(got, ite.Literal+" at "+ite.Pos.String())
+		}
+	}
+	if want := []string{"$ at 1:3", "? at 2:3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("joined errors = %q, want %q", got, want)
+	}
+}
+
+func TestReadErrorWithoutHook(t *testing.T) {
+	readErr := errors.New("disk on fire")
+	_, err := Tokenize(&failingReader{data: "1", err: readErr})
+	if !errors.Is(err, readErr) {
+		t.Errorf("Tokenize error = %v, want %v", err, readErr)
+	}
+}
+
+func TestOperatorClassification(t *testing.T) {
+	tests := []struct {
+		tok                  Token
+		add, mul, comparison bool
+		infix                bool
+	}{
+		{ADD, true, false, false, true},
+		{SUB, true, false, false, true},
+		{MUL, false, true, false, true},
+		{DIV, false, true, false, true},
+		{MOD, false, true, false, true},
+		{POW, false, false, false, true},
+		{DOTDOT, false, false, false, true},
+		{EQ, false, false, true, true},
+		{NEQ, false, false, true, true},
+		{LT, false, false, true, true},
+		{LE, false, false, true, true},
+		{GT, false, false, true, true},
+		{GE, false, false, true, true},
+		{AT, false, false, false, false},
+		{BANG, false, false, false, false},
+		{PERCENT, false, false, false, false},
+		{BAR, false, false, false, false},
+		{ASSIGN, false, false, false, false},
+		{LPAREN, false, false, false, false},
+		{INT, false, false, false, false},
+		{EOF, false, false, false, false},
+	}
+	for _, tt := range tests {
+		name := TokenName(tt.tok)
+		if got := isAddOp(tt.tok); got != tt.add {
+			t.Errorf("isAddOp(%s) = %t", name, got)
+		}
+		if got := isMulOp(tt.tok); got != tt.mul {
+			t.Errorf("isMulOp(%s) = %t", name, got)
+		}
+		if got := isComparisonOp(tt.tok); got != tt.comparison {
+			t.Errorf("isComparisonOp(%s) = %t", name, got)
+		}
+		if got := isInfixOp(tt.tok); got != tt.infix {
+			t.Errorf("isInfixOp(%s) = %t", name, got)
+		}
+	}
+}
+
+func TestAbsoluteValueBars(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"|-5|", "5"},
+		{"|-5| == 5", "true"},
+		{"|3 - 10| * 2", "14"},
+		{"||-3| - 5|", "2"},
+		{"|2 - |1 - 4||", "1"},
+		{"-|-2|", "-2"},
+	}
+	for _, tt := range tests {
+		v, err := EvalValue(tt.input)
+		if err != nil || v.String() != tt.want {
+			t.Errorf("EvalValue(%q) = %s, %v, want %s", tt.input, v, err, tt.want)
+		}
+	}
+	if got, want := mustParse(t, "||x|-1|").String(), "abs((abs(x) - 1))"; got != want {
+		t.Errorf("||x|-1| parsed as %s, want %s", got, want)
+	}
+	if _, err := Parse("|1 + 2"); err == nil {
+		t.Errorf("Parse(|1 + 2) did not fail")
+	}
+}
+
+func TestBracketGrouping(t *testing.T) {
+	if !Equal(mustParse(t, "[1+2]*3"), mustParse(t, "(1+2)*3")) {
+		t.Errorf("[1+2]*3 and (1+2)*3 parse differently")
+	}
+	if v, err := Eval("[(1 + 2) * [4 - 1]]"); err != nil || v != 9 {
+		t.Errorf("Eval of nested groupings = %d, %v, want 9", v, err)
+	}
+	for _, input := range []string{"(1+2]", "[1+2)", "[1+2"} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) did not fail", input)
+		}
+	}
+}
+
+func TestLeadingOperator(t *testing.T) {
+	for _, op := range []string{"*", "/", "%", "^", "**", "+", "==", "<", "!", ".."} {
+		input := op + "3"
+		want := fmt.Sprintf("unexpected operator '%s' at start of expression, expected a value at 1:1", op)
+		if _, err := Parse(input); err == nil || err.Error() != want {
+			t.Errorf("Parse(%q) error = %v, want %q", input, err, want)
+		}
+	}
+	if v, err := Eval("-3"); err != nil || v != -3 {
+		t.Errorf("Eval(-3) = %d, %v: unary minus is not a leading operator", v, err)
+	}
+}
+
+func TestRunTokens(t *testing.T) {
+	var out strings.Builder
+	if err := run([]string{"-tokens"}, strings.NewReader("1 + 2"), &out); err != nil {
+		t.Fatal(err)
+	}
+	want := "1:1\tINT\t1\n1:3\t+\t+\n1:5\tINT\t2\n"
+	if got := out.String(); got != want {
+		t.Errorf("-tokens output = %q, want %q", got, want)
+	}
+}
+
+func TestRunTokensComments(t *testing.T) {
+	var out strings.Builder
+	if err := run([]string{"-tokens"}, strings.NewReader("1 // c\n+ 2"), &out); err != nil {
+		t.Fatal(err)
+	}
+	want := "1:1\tINT\t1\n1:3\tCOMMENT\t// c\n2:1\t+\t+\n2:3\tINT\t2\n"
+	if got := out.String(); got != want {
+		t.Errorf("-tokens output = %q, want %q", got, want)
+	}
+}
+
+func TestRunScientific(t *testing.T) {
+	tests := []struct {
+		args  []string
+		input string
+		want  string
+	}{
+		{[]string{"-sci"}, "1500000.0 * 100000000000000.0", "(1500000.0 * 100000000000000.0)\n1.5e+20\n"},
+		{nil, "1500000.0 * 100000000000000.0", "(1500000.0 * 100000000000000.0)\n150000000000000000000.0\n"},
+		{[]string{"-sci"}, "1.0 / 4000", "(1.0 / 4000)\n2.5e-04\n"},
+		{[]string{"-sci"}, "6 * 7", "(6 * 7)\n42\n"},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		if err := run(tt.args, strings.NewReader(tt.input), &out); err != nil {
+			t.Errorf("run(%q, %q): %v", tt.args, tt.input, err)
+			continue
+		}
+		if got := out.String(); got != tt.want {
+			t.Errorf("run(%q, %q) output = %q, want %q", tt.args, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestRunAST(t *testing.T) {
+	var out strings.Builder
+	if err := run([]string{"-ast"}, strings.NewReader("1 + 2 * 3"), &out); err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("testdata/ast.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != string(want) {
+		t.Errorf("-ast output = %q, want %q", got, want)
+	}
+}
+
+func TestRunASTSyntaxError(t *testing.T) {
+	var out strings.Builder
+	err := run([]string{"-ast"}, strings.NewReader("1 + * 3"), &out)
+	if want := "unexpected operator '*', expected a value at 1:5"; err == nil || err.Error() != want {
+		t.Errorf("-ast error = %v, want %q", err, want)
+	}
+	if out.Len() != 0 {
+		t.Errorf("-ast printed %q for a syntax error", out.String())
+	}
+}
+
+func TestBinarySymbol(t *testing.T) {
+	tests := []struct {
+		op   Token
+		want string
+	}{
+		{ADD, "+"},
+		{SUB, "-"},
+		{MUL, "*"},
+		{DIV, "/"},
+		{MOD, "%"},
+		{POW, "^"},
+		{AT, "@"},
+		{EQ, "=="},
+		{NEQ, "!="},
+		{LT, "<"},
+		{LE, "<="},
+		{GT, ">"},
+		{GE, ">="},
+		{Token(-1), "Token(-1)"},
+		{Token(10000), "Token(10000)"},
+	}
+	for _, tt := range tests {
+		be := &BinaryExpression{Op: tt.op, Left: &IntegerLiteral{Value: 1}, Right: &IntegerLiteral{Value: 2}}
+		if got := be.Symbol(); got != tt.want {
+			t.Errorf("Symbol of %d = %q, want %q", tt.op, got, tt.want)
+		}
+		if got, want := be.String(), "(1 "+tt.want+" 2)"; got != want {
+			t.Errorf("String = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	tests := []struct {
+		input string
+		tok   Token
+	}{
+		{"let", LET},
+		{"lettuce", IDENT},
+		{"def", DEF},
+		{"default", IDENT},
+		{"as", AS},
+		{"ask", IDENT},
+		{"Let", IDENT},
+	}
+	for _, tt := range tests {
+		toks := newStringLexer(tt.input).Tokens()
+		if len(toks) != 1 || toks[0].Token != tt.tok || toks[0].Literal != tt.input {
+			t.Errorf("tokens of %q = %v, want one %s", tt.input, toks, TokenName(tt.tok))
+		}
+		tok, ok := IsKeyword(tt.input)
+		if ok != (tt.tok != IDENT) || ok && tok != tt.tok {
+			t.Errorf("IsKeyword(%q) = %s, %t", tt.input, TokenName(tok), ok)
+		}
+	}
+}
+
+func TestTokenName(t *testing.T) {
+	tests := []struct {
+		tok  Token
+		want string
+	}{
+		{IDENT, "IDENT"},
+		{ADD, "+"},
+		{LET, "let"},
+		{COMMENT, "COMMENT"},
+		{COMMENT + 1, fmt.Sprintf("Token(%d)", int(COMMENT)+1)},
+		{-1, "Token(-1)"},
+	}
+	for _, tt := range tests {
+		if got := TokenName(tt.tok); got != tt.want {
+			t.Errorf("TokenName(%d) = %q, want %q", int(tt.tok), got, tt.want)
+		}
+	}
+}
+
+func TestTrailingNewline(t *testing.T) {
+	for _, input := range []string{"1+2", "2 * (3 + 4)", "10 - 3 - 2", "x = 2; x * x", "5!", "50%"} {
+		want, wantErr := evalInput(input)
+		for _, variant := range []string{input + "\n", input + " \n\n", input + "\r\n"} {
+			got, err := evalInput(variant)
+			if got.String() != want.String() || fmt.Sprint(err) != fmt.Sprint(wantErr) {
+				t.Errorf("%q = %s, %v; %q = %s, %v", variant, got, err, input, want, wantErr)
+			}
+		}
+	}
+}
+
+func TestTrailingNewlineErrors(t *testing.T) {
+	for _, input := range []string{"1 +", "(1", "-", "f(1,"} {
+		_, want := Parse(input)
+		_, got := Parse(input + "\n")
+		if want == nil || fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Parse(%q) error = %v, Parse(%q) error = %v, want the same error", input+"\n", got, input, want)
+		}
+	}
+}
+
+// evalInput parses and evaluates input as a program, as main does.
+func evalInput(input string) (Value, error) {
+	program, err := ParseProgram(input)
+	if err != nil {
+		return Value{}, err
+	}
+	return EvaluateProgram(program, nil)
+}
+
+func TestMaxTokens(t *testing.T) {
+	l := newStringLexer("1 + 2 + 3 + 4")
+	l.MaxTokens = 4
+	toks := l.Tokens()
+	if len(toks) != 4 {
+		t.Errorf("tokens = %v, want the first 4", toks)
+	}
+	want := "too many tokens: the limit of 4 was reached at 1:9"
+	if err := l.Err(); err == nil || err.Error() != want {
+		t.Errorf("Err() = %v, want %q", err, want)
+	}
+
+	l = newStringLexer("1 + 2 + 3 + 4")
+	if toks := l.Tokens(); len(toks) != 7 || l.Err() != nil {
+		t.Errorf("unlimited lexer = %v, %v, want 7 tokens", toks, l.Err())
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+// EvaluateMemo evaluates expr like evaluateExpression, but computes each
+// distinct node only once. Nodes are keyed by identity, so subtrees shared
+// through Substitute or common-subexpression elimination are not re-walked.
+func EvaluateMemo(expr Expression, env map[string]int) (int, error) {
+	m := &memoEvaluator{ev: newEvaluator(env), results: make(map[Expression]Value)}
+	v, err := m.eval(expr)
+	if err != nil {
+		return 0, err
+	}
+	return v.integer()
+}
+
+type memoEvaluator struct {
+	ev      *evaluator
+	results map[Expression]Value
+}
+
+func (m *memoEvaluator) eval(expr Expression) (Value, error) {
+	if v, ok := m.results[expr]; ok {
+		return v, nil
+	}
+
+	var v Value
+	switch e := expr.(type) {
+	case *BinaryExpression:
+		left, err := m.eval(e.Left)
+		if err != nil {
+			return Value{}, err
+		}
+
+		right, err := m.eval(e.Right)
+		if err != nil {
+			return Value{}, err
+		}
+
+		v, err = m.ev.binary(e, left, right)
+		if err != nil {
+			return Value{}, err
+		}
+
+	default:
+		var err error
+		v, err = m.ev.eval(expr)
+		if err != nil {
+			return Value{}, err
+		}
+	}
+
+	m.results[expr] = v
+	return v, nil
+}
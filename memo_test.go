@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+// countingBuiltin registers a builtin named name for the duration of the test
+// and returns a pointer to the number of times it has been called.
+func countingBuiltin(t testing.TB, name string) *int {
+	t.Helper()
+	calls := new(int)
+	builtins[name] = builtin{0, 0, func([]Value) (Value, error) {
+		*calls++
+		return Value{Kind: IntValue, Int: 3}, nil
+	}}
+	t.Cleanup(func() { delete(builtins, name) })
+	return calls
+}
+
+func TestEvaluateMemoShared(t *testing.T) {
+	calls := countingBuiltin(t, "tick")
+
+	// (tick() + 1) * (tick() + 1), with the two operands the same node.
+	shared := &BinaryExpression{Left: &CallExpression{Function: "tick"}, Op: ADD, Right: &IntegerLiteral{Value: 1}}
+	expr := &BinaryExpression{Left: shared, Op: MUL, Right: shared}
+
+	v, err := EvaluateMemo(expr, nil)
+	if err != nil || v != 16 {
+		t.Fatalf("EvaluateMemo = %d, %v, want 16", v, err)
+	}
+	if *calls != 1 {
+		t.Errorf("EvaluateMemo called tick %d times, want 1", *calls)
+	}
+
+	*calls = 0
+	if _, err := evaluateExpression(expr, nil); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 2 {
+		t.Errorf("evaluateExpression called tick %d times, want 2", *calls)
+	}
+}
+
+func TestEvaluateMemoDistinct(t *testing.T) {
+	calls := countingBuiltin(t, "tick")
+
+	// Equal but distinct nodes are each evaluated.
+	expr := mustParse(t, "tick() + tick()")
+	if v, err := EvaluateMemo(expr, nil); err != nil || v != 6 {
+		t.Fatalf("EvaluateMemo = %d, %v, want 6", v, err)
+	}
+	if *calls != 2 {
+		t.Errorf("EvaluateMemo called tick %d times, want 2", *calls)
+	}
+}
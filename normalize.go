@@ -0,0 +1,121 @@
+package main
+
+import "sort"
+
+// Normalize returns a copy of expr in which the operands of every + and *
+// are put in a canonical order, so that trees differing only by commuted
+// operands, such as 2 + x and x + 2, normalize to Equal trees. Operands are
+// ordered by their String form.
+//
+// A chain of the same operator, such as x + 2 + y, is flattened, sorted and
+// rebuilt left-nested, so that it normalizes to the same tree as y + 2 + x.
+// Regrouping a chain relies on associativity, which integer arithmetic has
+// but float arithmetic does not, so only chains whose operands are all
+// integers are regrouped; in any other chain just the two operands of each
+// node are ordered. Only numeric operands are swapped at all, as in
+// Simplify: + also concatenates strings, which does not commute. Other
+// operators keep their operand order.
+func Normalize(expr Expression) Expression {
+	switch e := expr.(type) {
+	case *BinaryExpression:
+		if e.Op == ADD || e.Op == MUL {
+			if operands := chainOperands(e, nil); operands != nil {
+				return rebuildChain(e, operands)
+			}
+		}
+		left, right := Normalize(e.Left), Normalize(e.Right)
+		if (e.Op == ADD || e.Op == MUL) && isNumeric(left) && isNumeric(right) && right.String() < left.String() {
+			left, right = right, left
+		}
+		return &BinaryExpression{Left: left, Op: e.Op, Right: right, Position: e.Position, EndPosition: e.EndPosition}
+	case *NaryExpression:
+		return &NaryExpression{Op: e.Op, Operands: normalizeAll(e.Operands), Position: e.Position, EndPosition: e.EndPosition}
+	case *UnaryExpression:
+		copied := *e
+		copied.Operand = Normalize(e.Operand)
+		return &copied
+	case *CastExpression:
+		copied := *e
+		copied.Operand = Normalize(e.Operand)
+		return &copied
+	case *RangeExpression:
+		copied := *e
+		copied.From = Normalize(e.From)
+		copied.To = Normalize(e.To)
+		return &copied
+	case *ExpressionList:
+		return &ExpressionList{Elements: normalizeAll(e.Elements), Position: e.Position, EndPosition: e.EndPosition}
+	case *CallExpression:
+		return &CallExpression{Function: e.Function, Arguments: normalizeAll(e.Arguments), Position: e.Position, EndPosition: e.EndPosition}
+	case *Assignment:
+		copied := *e
+		copied.Value = Normalize(e.Value)
+		return &copied
+	case *FunctionDefinition:
+		copied := *e
+		copied.Body = Normalize(e.Body)
+		return &copied
+	default:
+		return Substitute(expr, nil)
+	}
+}
+
+func normalizeAll(exprs []Expression) []Expression {
+	out := make([]Expression, len(exprs))
+	for i, expr := range exprs {
+		out[i] = Normalize(expr)
+	}
+	return out
+}
+
+// chainOperands appends the normalized operands of the chain of e.Op rooted at
+// e to operands, or returns nil if any of them might not be an integer.
+func chainOperands(e *BinaryExpression, operands []Expression) []Expression {
+	for _, side := range []Expression{e.Left, e.Right} {
+		if be, ok := side.(*BinaryExpression); ok && be.Op == e.Op {
+			if operands = chainOperands(be, operands); operands == nil {
+				return nil
+			}
+			continue
+		}
+		if !isIntegral(side) {
+			return nil
+		}
+		operands = append(operands, Normalize(side))
+	}
+	return operands
+}
+
+// rebuildChain sorts the operands of the chain rooted at e and nests them to
+// the left, as the parser would.
+func rebuildChain(e *BinaryExpression, operands []Expression) Expression {
+	sort.SliceStable(operands, func(i, j int) bool {
+		return operands[i].String() < operands[j].String()
+	})
+	result := operands[0]
+	for _, operand := range operands[1:] {
+		result = &BinaryExpression{Left: result, Op: e.Op, Right: operand, Position: e.Position, EndPosition: e.EndPosition}
+	}
+	return result
+}
+
+// isIntegral reports whether expr, if it evaluates at all, evaluates to an
+// integer, with identifiers assumed to be bound to integers as in Simplify.
+func isIntegral(expr Expression) bool {
+	switch e := expr.(type) {
+	case *IntegerLiteral:
+		return true
+	case *Identifier:
+		return !isConstant(e)
+	case *CastExpression:
+		return e.Type == "int"
+	case *UnaryExpression:
+		return e.Op == SUB && !e.Postfix && isIntegral(e.Operand)
+	case *BinaryExpression:
+		switch e.Op {
+		case ADD, SUB, MUL, DIV, MOD:
+			return isIntegral(e.Left) && isIntegral(e.Right)
+		}
+	}
+	return false
+}
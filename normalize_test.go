@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestNormalizeCommutes(t *testing.T) {
+	tests := []struct{ a, b string }{
+		{"2 + x", "x + 2"},
+		{"2 * x", "x * 2"},
+		{"x + 2 + y", "y + 2 + x"},
+		{"x + 2 + y", "x + (y + 2)"},
+		{"a * b * c * d", "d * (c * b) * a"},
+		{"(x - 1) * y + 3", "3 + y * (x - 1)"},
+		{"f(x + 1)", "f(1 + x)"},
+		{"1.5 + x", "x + 1.5"},
+	}
+	for _, tt := range tests {
+		a, b := normalized(t, tt.a), normalized(t, tt.b)
+		if !Equal(a, b) {
+			t.Errorf("Normalize(%s) = %s, Normalize(%s) = %s, want Equal trees", tt.a, a, tt.b, b)
+		}
+	}
+}
+
+func TestNormalizePreserves(t *testing.T) {
+	tests := []struct{ a, b string }{
+		{"x - 2", "2 - x"},
+		{"x / 2", "2 / x"},
+		{"x % 2", "2 % x"},
+		{"\"a\" + \"b\"", "\"b\" + \"a\""},
+		// Float addition is not associative, so the chain is not regrouped.
+		{"1.5 + x + y", "1.5 + (x + y)"},
+	}
+	for _, tt := range tests {
+		a, b := normalized(t, tt.a), normalized(t, tt.b)
+		if Equal(a, b) {
+			t.Errorf("Normalize(%s) and Normalize(%s) are both %s, want them to differ", tt.a, tt.b, a)
+		}
+	}
+}
+
+func TestNormalizeChain(t *testing.T) {
+	got := normalized(t, "y + 2 + x").String()
+	if want := "((2 + x) + y)"; got != want {
+		t.Errorf("Normalize(y + 2 + x) = %s, want %s", got, want)
+	}
+}
+
+func normalized(t *testing.T, input string) Expression {
+	t.Helper()
+	expr, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", input, err)
+	}
+	return Normalize(expr)
+}
@@ -0,0 +1,45 @@
+package main
+
+import "fmt"
+
+// Precedence levels for RegisterBinaryOp, loosest first. An operator
+// registered at a level is left-associative and binds like the built-in
+// operators of that level.
+const (
+	PrecComparison = iota + 1 // == != < <= > >=
+	PrecSum                   // + -
+	PrecProduct               // * / %
+)
+
+type binaryOp struct {
+	prec int
+	fn   func(a, b Value) (Value, error)
+}
+
+var binaryOps = map[Token]binaryOp{}
+
+// RegisterBinaryOp gives the operator token tok a meaning as a binary
+// operator at precedence prec, evaluated by fn. tok must be an operator the
+// grammar does not already use, such as AT. RegisterBinaryOp is meant to be
+// called from init functions; it must not run concurrently with parsing or
+// evaluation. It panics if tok or prec is not valid.
+func RegisterBinaryOp(tok Token, prec int, fn func(a, b Value) (Value, error)) {
+	if tok != AT {
+		panic(fmt.Sprintf("RegisterBinaryOp: %s is already an operator", TokenName(tok)))
+	}
+	if prec < PrecComparison || prec > PrecProduct {
+		panic(fmt.Sprintf("RegisterBinaryOp: invalid precedence %d", prec))
+	}
+	binaryOps[tok] = binaryOp{prec: prec, fn: fn}
+}
+
+func isCustomOp(tok Token) bool {
+	_, ok := binaryOps[tok]
+	return ok
+}
+
+// customPrec returns the precedence of a registered operator, or 0 if tok
+// has none.
+func customPrec(tok Token) int {
+	return binaryOps[tok].prec
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// registerAt registers AT as a*10 + b at prec for the rest of t.
+func registerAt(t *testing.T, prec int) {
+	t.Helper()
+	RegisterBinaryOp(AT, prec, func(a, b Value) (Value, error) {
+		if a.Kind != IntValue || b.Kind != IntValue {
+			return Value{}, fmt.Errorf("@ needs ints")
+		}
+		return Value{Kind: IntValue, Int: a.Int*10 + b.Int}, nil
+	})
+	t.Cleanup(func() { delete(binaryOps, AT) })
+}
+
+func TestRegisterBinaryOp(t *testing.T) {
+	registerAt(t, PrecProduct)
+	checkResults(t, []struct{ input, want string }{
+		{"2 @ 3", "23"},
+		{"1 + 2 @ 3", "24"},
+		{"1 @ 2 @ 3", "123"},
+	})
+	checkErrors(t, []struct{ input, want string }{
+		{"2 @ 0.5", "@ needs ints"},
+	})
+	if got := mustParse(t, "1 + 2 @ 3").String(); got != "(1 + (2 @ 3))" {
+		t.Errorf("Parse(1 + 2 @ 3) = %s, want (1 + (2 @ 3))", got)
+	}
+}
+
+func TestRegisterBinaryOpPrecedence(t *testing.T) {
+	registerAt(t, PrecSum)
+	if got := mustParse(t, "1 * 2 @ 3").String(); got != "((1 * 2) @ 3)" {
+		t.Errorf("Parse(1 * 2 @ 3) = %s, want ((1 * 2) @ 3)", got)
+	}
+}
+
+func TestRegisterBinaryOpPanics(t *testing.T) {
+	for _, tt := range []struct {
+		tok  Token
+		prec int
+	}{
+		{ADD, PrecSum},
+		{AT, 0},
+		{AT, PrecProduct + 1},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterBinaryOp(%s, %d) did not panic", TokenName(tt.tok), tt.prec)
+				}
+			}()
+			RegisterBinaryOp(tt.tok, tt.prec, nil)
+		}()
+	}
+	if isCustomOp(AT) || isCustomOp(ADD) {
+		t.Error("a rejected registration took effect")
+	}
+}
+
+func TestUnregisteredOperator(t *testing.T) {
+	if _, err := Parse("2 @ 3"); err == nil {
+		t.Error("Parse(2 @ 3) succeeded without a registered @")
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+// EvalOption configures Evaluate.
+type EvalOption func(*evalConfig)
+
+type evalConfig struct {
+	env  map[string]int
+	opts EvalOptions
+}
+
+// WithEnv resolves identifiers in env.
+func WithEnv(env map[string]int) EvalOption {
+	return func(c *evalConfig) {
+		c.env = env
+	}
+}
+
+// WithDivByZeroPolicy sets what division and modulo by zero produce.
+func WithDivByZeroPolicy(p DivByZeroPolicy) EvalOption {
+	return func(c *evalConfig) {
+		c.opts.DivByZero = p
+	}
+}
+
+// WithExactDivision sets whether integer division that leaves a remainder
+// is an error rather than truncating.
+func WithExactDivision(exact bool) EvalOption {
+	return func(c *evalConfig) {
+		c.opts.ExactDivision = exact
+	}
+}
+
+// WithMaxDepth bounds nested function calls at n.
+func WithMaxDepth(n int) EvalOption {
+	return func(c *evalConfig) {
+		c.opts.MaxDepth = n
+	}
+}
+
+// WithOptions replaces every setting but the environment with opts, for the
+// settings that have no option function of their own. Options after it
+// adjust the result.
+func WithOptions(opts EvalOptions) EvalOption {
+	return func(c *evalConfig) {
+		c.opts = opts
+	}
+}
+
+// Evaluate evaluates expr to an int as configured by opts, failing if the
+// result is of another kind. With no options it behaves like
+// evaluateExpression.
+func Evaluate(expr Expression, opts ...EvalOption) (int, error) {
+	var c evalConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	v, err := EvaluateValueWith(expr, c.env, c.opts)
+	if err != nil {
+		return 0, err
+	}
+	return v.integer()
+}
+
+// LexerOption configures a Lexer made by NewLexer.
+type LexerOption func(*Lexer)
+
+// WithTabWidth sets the lexer's TabWidth.
+func WithTabWidth(n int) LexerOption {
+	return func(l *Lexer) {
+		l.TabWidth = n
+	}
+}
+
+// WithEmitTrivia sets whether the lexer returns comments as COMMENT tokens.
+func WithEmitTrivia(emit bool) LexerOption {
+	return func(l *Lexer) {
+		l.EmitTrivia = emit
+	}
+}
+
+// WithCommentPrefix makes prefixes the only strings that start a comment.
+func WithCommentPrefix(prefixes ...string) LexerOption {
+	return func(l *Lexer) {
+		l.CommentPrefixes = prefixes
+	}
+}
+
+// ParseOption configures Parse, ParseTokens and ParseProgram.
+type ParseOption func(*parser)
+
+// WithPreserveLiterals sets whether number literals keep their source text,
+// so that String prints 1_000_000 or 0xff as written rather than in
+// canonical decimal form.
+func WithPreserveLiterals(preserve bool) ParseOption {
+	return func(p *parser) {
+		p.preserveLiterals = preserve
+	}
+}
+
+// WithMaxParseDepth limits how deeply expressions may nest to n levels. Zero
+// or less means DefaultMaxParseDepth.
+func WithMaxParseDepth(n int) ParseOption {
+	return func(p *parser) {
+		if n <= 0 {
+			n = DefaultMaxParseDepth
+		}
+		p.maxDepth = n
+	}
+}
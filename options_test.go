@@ -0,0 +1,123 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEvaluateOptions(t *testing.T) {
+	env := map[string]int{"x": 7, "y": 0}
+	tests := []struct {
+		input string
+		opts  []EvalOption
+		want  int
+	}{
+		{"1 + 2", nil, 3},
+		{"x / 2", []EvalOption{WithEnv(env)}, 3},
+		{"x / y + 1", []EvalOption{WithEnv(env), WithDivByZeroPolicy(DivByZeroZero)}, 1},
+		{"x * 2 / 7", []EvalOption{WithEnv(env), WithExactDivision(true), WithMaxDepth(1)}, 2},
+		{"z + 1", []EvalOption{WithOptions(EvalOptions{LenientVars: true}), WithDivByZeroPolicy(DivByZeroZero)}, 1},
+		{"1 / 0 + z", []EvalOption{WithOptions(EvalOptions{LenientVars: true}), WithDivByZeroPolicy(DivByZeroZero)}, 0},
+	}
+	for _, tt := range tests {
+		got, err := Evaluate(mustParse(t, tt.input), tt.opts...)
+		if err != nil || got != tt.want {
+			t.Errorf("Evaluate(%q) = %d, %v, want %d", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestEvaluateOptionErrors(t *testing.T) {
+	env := map[string]int{"x": 7, "y": 0}
+	tests := []struct {
+		input string
+		opts  []EvalOption
+		want  string
+	}{
+		{"x / y", []EvalOption{WithEnv(env)}, "division by zero"},
+		{"x / 2", []EvalOption{WithEnv(env), WithExactDivision(true)}, "non-exact division 7 / 2 at 1:1"},
+		{"z", []EvalOption{WithOptions(EvalOptions{LenientVars: true}), WithOptions(EvalOptions{})}, "undefined variable z"},
+	}
+	for _, tt := range tests {
+		_, err := Evaluate(mustParse(t, tt.input), tt.opts...)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Evaluate(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestWithMaxDepth(t *testing.T) {
+	var c evalConfig
+	WithMaxDepth(3)(&c)
+	WithExactDivision(true)(&c)
+	if c.opts.MaxDepth != 3 || !c.opts.ExactDivision {
+		t.Errorf("options = %+v, want MaxDepth 3 and ExactDivision", c.opts)
+	}
+}
+
+func TestLexerDefaults(t *testing.T) {
+	l := newStringLexer("")
+	if l.TabWidth != 0 || l.EmitTrivia || l.MaxLiteralLength != DefaultMaxLiteralLength ||
+		!reflect.DeepEqual(l.CommentPrefixes, DefaultCommentPrefixes) {
+		t.Errorf("NewLexer settings = TabWidth %d, EmitTrivia %t, MaxLiteralLength %d, CommentPrefixes %q",
+			l.TabWidth, l.EmitTrivia, l.MaxLiteralLength, l.CommentPrefixes)
+	}
+	if got := lexed("1 # a\n2 // b"); got != "1 2" {
+		t.Errorf("default lexer = %q, want comments skipped", got)
+	}
+}
+
+func TestLexerOptions(t *testing.T) {
+	tests := []struct {
+		input string
+		opts  []LexerOption
+		want  string
+	}{
+		{"1 # a\n2 // b", []LexerOption{WithEmitTrivia(true)}, "1 # a 2 // b"},
+		{"1 -- a\n2 // b", []LexerOption{WithCommentPrefix("--")}, "1 2 / / b"},
+		{"1 -- a", []LexerOption{WithCommentPrefix("--"), WithEmitTrivia(true)}, "1 -- a"},
+	}
+	for _, tt := range tests {
+		if got := lexed(tt.input, tt.opts...); got != tt.want {
+			t.Errorf("lexed(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		opts []LexerOption
+		want string
+	}{
+		{nil, "1:2"},
+		{[]LexerOption{WithTabWidth(4)}, "1:5"},
+		{[]LexerOption{WithTabWidth(8)}, "1:9"},
+	} {
+		toks := newStringLexer("\tx", tt.opts...).Tokens()
+		if len(toks) != 1 || toks[0].Pos.String() != tt.want {
+			t.Errorf("tokens = %v, want x at %s", toks, tt.want)
+		}
+	}
+}
+
+func TestWithExactDivision(t *testing.T) {
+	exact := WithExactDivision(true)
+	for _, tt := range []struct {
+		input string
+		want  int
+	}{
+		{"6/2", 3},
+		{"-8 / 4", -2},
+		{"0 / 5", 0},
+	} {
+		if got, err := Evaluate(mustParse(t, tt.input), exact); err != nil || got != tt.want {
+			t.Errorf("Evaluate(%q) = %d, %v, want %d", tt.input, got, err, tt.want)
+		}
+	}
+
+	_, err := Evaluate(mustParse(t, "7/2"), exact)
+	if want := "non-exact division 7 / 2 at 1:1"; err == nil || err.Error() != want {
+		t.Errorf("Evaluate(7/2) error = %v, want %q", err, want)
+	}
+	if got, err := Evaluate(mustParse(t, "7/2"), WithExactDivision(false)); err != nil || got != 3 {
+		t.Errorf("Evaluate(7/2) without exact division = %d, %v, want 3", got, err)
+	}
+}
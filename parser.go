@@ -0,0 +1,97 @@
+package main
+
+import "fmt"
+
+// Tokenizer is a source of tokens for the parser. *Lexer is one; another can
+// feed the parser tokens from elsewhere, such as a slice built by hand. Next
+// returns successive tokens, then EOF on every call once the input is
+// exhausted. The parser keeps its own lookahead, so a Tokenizer need not
+// support peeking or pushing tokens back.
+type Tokenizer interface {
+	Next() TokenInfo
+}
+
+// parser adds the one token of lookahead the grammar needs to a Tokenizer.
+type parser struct {
+	tokens Tokenizer
+
+	// end is the position just past the last token returned by Lex.
+	end Position
+
+	peeked bool
+	peek   TokenInfo
+
+	// consumed counts the tokens returned by Lex, and last is the latest.
+	consumed int
+	last     Token
+
+	// preserveLiterals keeps the source text of number literals.
+	preserveLiterals bool
+
+	// depth is how deeply the expression being parsed is nested.
+	depth    int
+	maxDepth int
+}
+
+// DefaultMaxParseDepth is how deeply expressions may nest, in parentheses,
+// arguments, prefix minus signs and exponents, unless WithMaxParseDepth says
+// otherwise. It is far beyond any formula written by hand but keeps hostile
+// input from exhausting the stack.
+const DefaultMaxParseDepth = 10000
+
+func newParser(t Tokenizer, opts ...ParseOption) *parser {
+	p := &parser{tokens: t, maxDepth: DefaultMaxParseDepth}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// enter records one more level of nesting, starting at pos, and fails if
+// that exceeds the limit. Each successful enter is paired with a leave.
+func (p *parser) enter(pos Position) error {
+	if p.depth >= p.maxDepth {
+		return fmt.Errorf("expression nested more than %d levels deep at %s", p.maxDepth, pos)
+	}
+	p.depth++
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+// Lex consumes the next token.
+func (p *parser) Lex() (Position, Token, string) {
+	if !p.peeked {
+		p.peek = p.tokens.Next()
+	}
+	p.peeked = false
+	p.end = p.peek.End
+	p.consumed++
+	p.last = p.peek.Token
+	return p.peek.Pos, p.peek.Token, p.peek.Literal
+}
+
+// Peek returns the next token without consuming it.
+func (p *parser) Peek() (Position, Token, string) {
+	if !p.peeked {
+		p.peek = p.tokens.Next()
+		p.peeked = true
+	}
+	return p.peek.Pos, p.peek.Token, p.peek.Literal
+}
+
+// ParseTokens parses the tokens t produces as a single expression, failing if
+// any token but EOF follows it.
+func ParseTokens(t Tokenizer, opts ...ParseOption) (Expression, error) {
+	p := newParser(t, opts...)
+	expr, err := parseExpression(p)
+	if err != nil {
+		return nil, err
+	}
+	if pos, tok, lit := p.Lex(); tok != EOF {
+		return nil, unexpected(pos, tok, lit)
+	}
+	return expr, nil
+}
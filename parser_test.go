@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// sliceTokenizer is a Tokenizer over a fixed slice of tokens, each on its own
+// column of line 1.
+type sliceTokenizer struct {
+	toks []TokenInfo
+}
+
+func tokenizerOf(toks ...TokenInfo) *sliceTokenizer {
+	for i := range toks {
+		toks[i].Pos = Position{line: 1, column: i + 1, offset: i}
+		toks[i].End = Position{line: 1, column: i + 2, offset: i + 1}
+	}
+	return &sliceTokenizer{toks: toks}
+}
+
+func (s *sliceTokenizer) Next() TokenInfo {
+	if len(s.toks) == 0 {
+		return TokenInfo{Token: EOF}
+	}
+	t := s.toks[0]
+	s.toks = s.toks[1:]
+	return t
+}
+
+func TestParseTokens(t *testing.T) {
+	expr, err := ParseTokens(tokenizerOf(
+		TokenInfo{Token: INT, Literal: "2"},
+		TokenInfo{Token: MUL, Literal: "*"},
+		TokenInfo{Token: LPAREN, Literal: "("},
+		TokenInfo{Token: IDENT, Literal: "x"},
+		TokenInfo{Token: ADD, Literal: "+"},
+		TokenInfo{Token: INT, Literal: "1"},
+		TokenInfo{Token: RPAREN, Literal: ")"},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := expr.String(), "(2 * (x + 1))"; got != want {
+		t.Errorf("ParseTokens = %s, want %s", got, want)
+	}
+	if v, err := evaluateExpression(expr, map[string]int{"x": 4}); err != nil || v != 10 {
+		t.Errorf("evaluate = %d, %v, want 10", v, err)
+	}
+}
+
+func TestParseTokensErrors(t *testing.T) {
+	_, err := ParseTokens(tokenizerOf(
+		TokenInfo{Token: INT, Literal: "1"},
+		TokenInfo{Token: INT, Literal: "2"},
+	))
+	if want := "unexpected token INT at 1:2"; err == nil || err.Error() != want {
+		t.Errorf("ParseTokens(1 2) error = %v, want %q", err, want)
+	}
+}
+
+func TestWithMaxParseDepth(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"((((1))))", "expression nested more than 3 levels deep at 1:4"},
+		{"1 + (2 * (3 - (4)))", "expression nested more than 3 levels deep at 1:16"},
+		{"---1", "expression nested more than 3 levels deep at 1:3"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.input, WithMaxParseDepth(3))
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+	if _, err := Parse("((1))", WithMaxParseDepth(3)); err != nil {
+		t.Errorf("Parse((1)) within the limit: %v", err)
+	}
+
+	deep := strings.Repeat("(", DefaultMaxParseDepth+1) + "1" + strings.Repeat(")", DefaultMaxParseDepth+1)
+	if _, err := Parse(deep, WithMaxParseDepth(0)); err == nil {
+		t.Error("Parse beyond DefaultMaxParseDepth succeeded")
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// Program is a sequence of statements separated by semicolons. A statement
+// is an expression, a *FunctionDefinition or an *Assignment.
+type Program struct {
+	Statements []Expression
+}
+
+func (p *Program) Pos() Position {
+	if len(p.Statements) == 0 {
+		return Position{}
+	}
+	return p.Statements[0].Pos()
+}
+
+func (p *Program) End() Position {
+	if len(p.Statements) == 0 {
+		return Position{}
+	}
+	return p.Statements[len(p.Statements)-1].End()
+}
+
+func (p *Program) String() string {
+	stmts := make([]string, len(p.Statements))
+	for i, stmt := range p.Statements {
+		stmts[i] = stmt.String()
+	}
+	return strings.Join(stmts, "; ")
+}
+
+// ParseProgram parses the whole of input as a program. Empty statements, such
+// as a trailing semicolon, are skipped.
+//
+// A statement that fails to parse does not stop the parse: it is skipped up
+// to the next semicolon and the statements after it are parsed as usual. The
+// result then holds every statement that parsed, and the error is an
+// ErrorList with an error for each one that did not, in input order. An
+// error from reading the input is returned alone, with no program.
+func ParseProgram(input string, opts ...ParseOption) (*Program, error) {
+	l := NewLexer(bufio.NewReader(strings.NewReader(input)))
+	program, err := parseProgram(newParser(l, opts...))
+	if l.Err() != nil {
+		// The lexer ended the input early, which explains any parse error.
+		return nil, l.Err()
+	}
+	return program, err
+}
+
+// ParseStatements is ParseProgram under a name that pairs it with ParseExpr:
+// it accepts any number of statements separated by semicolons.
+func ParseStatements(input string, opts ...ParseOption) (*Program, error) {
+	return ParseProgram(input, opts...)
+}
+
+func parseProgram(p *parser) (*Program, error) {
+	program := &Program{}
+	var errs ErrorList
+	for {
+		skipSemicolons(p)
+		start := p.consumed
+		stmt, err := parseStatement(p)
+		if err != nil {
+			errs = append(errs, err)
+			if p.consumed == start || (p.last != SEMICOLON && p.last != EOF) {
+				skipStatement(p)
+			}
+			continue
+		}
+		if stmt == nil {
+			break
+		}
+		program.Statements = append(program.Statements, stmt)
+	}
+	if errs != nil {
+		return program, errs
+	}
+	return program, nil
+}
+
+// skipStatement discards the tokens up to and including the next semicolon,
+// so that parsing can resume after a statement with an error.
+func skipStatement(p *parser) {
+	for {
+		if _, tok, _ := p.Lex(); tok == SEMICOLON || tok == EOF {
+			return
+		}
+	}
+}
+
+func skipSemicolons(p *parser) {
+	for {
+		if _, tok, _ := p.Peek(); tok != SEMICOLON {
+			return
+		}
+		p.Lex()
+	}
+}
+
+// ErrorList is the errors from the statements of a program that failed to
+// parse. Its message is that of the first error, so a program with a single
+// bad statement fails with the same message as before recovery.
+type ErrorList []error
+
+func (list ErrorList) Error() string {
+	switch len(list) {
+	case 0:
+		return "no errors"
+	case 1:
+		return list[0].Error()
+	case 2:
+		return fmt.Sprintf("%s (and 1 more error)", list[0])
+	}
+	return fmt.Sprintf("%s (and %d more errors)", list[0], len(list)-1)
+}
+
+// Unwrap returns the errors in the list, so that errors.Is and errors.As
+// look at each of them.
+func (list ErrorList) Unwrap() []error {
+	return list
+}
+
+// parseStatement parses the next statement and the semicolon after it, if
+// any, skipping empty statements. It returns nil at the end of the input.
+func parseStatement(p *parser) (Expression, error) {
+	skipSemicolons(p)
+
+	var stmt Expression
+	var err error
+	switch _, tok, _ := p.Peek(); tok {
+	case EOF:
+		return nil, nil
+	case DEF:
+		stmt, err = parseFunctionDefinition(p)
+	case LET:
+		stmt, err = parseLet(p)
+	default:
+		stmt, err = parseExpression(p)
+		if id, ok := stmt.(*Identifier); ok {
+			if _, tok, _ := p.Peek(); tok == ASSIGN {
+				stmt, err = parseAssignment(p, id)
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch pos, tok, lit := p.Peek(); tok {
+	case EOF:
+	case SEMICOLON:
+		p.Lex()
+	default:
+		return nil, unexpected(pos, tok, lit)
+	}
+	return stmt, nil
+}
+
+// EvaluateProgram evaluates each statement in order and returns the value of
+// the last expression, or the zero Value if there is none. Functions defined
+// by earlier statements are callable from later ones.
+func EvaluateProgram(p *Program, env map[string]int) (Value, error) {
+	return newEvaluator(env).run(p)
+}
+
+// run evaluates p in ev's environment, leaving its definitions there.
+func (ev *evaluator) run(p *Program) (Value, error) {
+	var result Value
+	for _, stmt := range p.Statements {
+		v, ok, err := ev.exec(stmt)
+		if err != nil {
+			return Value{}, err
+		}
+		if ok {
+			result = v
+		}
+	}
+	return result, nil
+}
+
+// exec evaluates one statement. ok is false for a definition, which has no
+// value.
+func (ev *evaluator) exec(stmt Expression) (v Value, ok bool, err error) {
+	switch s := stmt.(type) {
+	case *FunctionDefinition:
+		ev.env.Define(s)
+		return Value{}, false, nil
+	case *Assignment:
+		if v, err = ev.eval(s.Value); err != nil {
+			return Value{}, false, err
+		}
+		ev.env.Set(s.Name, v)
+		return v, true, nil
+	}
+	if v, err = ev.eval(stmt); err != nil {
+		return Value{}, false, err
+	}
+	return v, true, nil
+}
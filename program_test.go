@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseProgram(t *testing.T) {
+	program, err := ParseProgram("1 + 2; 3 * 4;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(program.Statements); n != 2 {
+		t.Fatalf("got %d statements, want 2", n)
+	}
+	if got, want := program.String(), "(1 + 2); (3 * 4)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	v, err := EvaluateProgram(program, nil)
+	if err != nil || v.Kind != IntValue || v.Int != 12 {
+		t.Errorf("EvaluateProgram = %s, %v, want 12", v, err)
+	}
+}
+
+func TestParseProgramEmpty(t *testing.T) {
+	for _, input := range []string{"", ";", " ; ;; "} {
+		program, err := ParseProgram(input)
+		if err != nil || len(program.Statements) != 0 {
+			t.Errorf("ParseProgram(%q) = %v, %v, want no statements", input, program, err)
+		}
+	}
+}
+
+func TestParseExprRejectsStatements(t *testing.T) {
+	_, err := ParseExpr("1;2")
+	if want := "unexpected token ; at 1:2"; err == nil || err.Error() != want {
+		t.Errorf("ParseExpr(1;2) error = %v, want %q", err, want)
+	}
+
+	program, err := ParseStatements("1;2")
+	if err != nil {
+		t.Fatalf("ParseStatements(1;2): %v", err)
+	}
+	if len(program.Statements) != 2 || program.String() != "1; 2" {
+		t.Errorf("ParseStatements(1;2) = %s with %d statements, want 1; 2", program, len(program.Statements))
+	}
+}
+
+func TestParseProgramRecovery(t *testing.T) {
+	program, err := ParseProgram("x = 1 + 2; y = * 3; x * 2")
+	if program == nil || len(program.Statements) != 2 || program.String() != "x = (1 + 2); (x * 2)" {
+		t.Fatalf("ParseProgram = %v, want the two good statements", program)
+	}
+	var list ErrorList
+	if !errors.As(err, &list) || len(list) != 1 {
+		t.Fatalf("ParseProgram error = %v, want an ErrorList with one error", err)
+	}
+	if want := "unexpected operator '*' at start of expression, expected a value at 1:16"; list[0].Error() != want {
+		t.Errorf("error = %q, want %q", list[0], want)
+	}
+}
+
+func TestParseProgramErrorList(t *testing.T) {
+	_, err := ParseProgram("1 +; 2; (3; 4 4")
+	var list ErrorList
+	if !errors.As(err, &list) || len(list) != 3 {
+		t.Fatalf("ParseProgram error = %v, want 3 errors", err)
+	}
+	want := "unexpected token ; at 1:4 (and 2 more errors)"
+	if err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
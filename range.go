@@ -0,0 +1,52 @@
+package main
+
+import "fmt"
+
+// maxRangeLength bounds the sequences a range may produce, since every
+// element is materialized.
+const maxRangeLength = 1_000_000
+
+// rangeCheckInterval is how many elements evalRange builds between checks
+// for cancellation.
+const rangeCheckInterval = 4096
+
+// evalRange evaluates from..to to the integers between them inclusive. When
+// from is greater than to the sequence counts down, so 3..1 is (3, 2, 1) and
+// a range is never empty.
+func (ev *evaluator) evalRange(r *RangeExpression) (Value, error) {
+	from, err := ev.eval(r.From)
+	if err != nil {
+		return Value{}, err
+	}
+	to, err := ev.eval(r.To)
+	if err != nil {
+		return Value{}, err
+	}
+	if from.Kind != IntValue || to.Kind != IntValue {
+		return Value{}, fmt.Errorf("range bounds must be integers, got %s and %s", from.Kind, to.Kind)
+	}
+
+	// The distance between the bounds is computed in uint64, where it cannot
+	// overflow: to.Int-from.Int wraps for bounds such as MinInt..MaxInt.
+	step, n := 1, uint64(to.Int)-uint64(from.Int)
+	if to.Int < from.Int {
+		step, n = -1, uint64(from.Int)-uint64(to.Int)
+	}
+	if n >= maxRangeLength {
+		return Value{}, fmt.Errorf("range %s..%s is longer than %d elements", from, to, maxRangeLength)
+	}
+
+	seq := make([]Value, 0, n+1)
+	for i := from.Int; ; i += step {
+		if len(seq)%rangeCheckInterval == 0 {
+			if err := ev.interrupted(); err != nil {
+				return Value{}, err
+			}
+		}
+		seq = append(seq, Value{Kind: IntValue, Int: i})
+		if i == to.Int {
+			break
+		}
+	}
+	return Value{Kind: SeqValue, Seq: seq}, nil
+}
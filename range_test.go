@@ -0,0 +1,43 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestRange(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1..3", "(1, 2, 3)"},
+		{"3..1", "(3, 2, 1)"},
+		{"2..2", "(2)"},
+		{"sum(1..5)", "15"},
+		{"sum(5..1)", "15"},
+	}
+	for _, tt := range tests {
+		v, err := EvalValue(tt.input)
+		if err != nil {
+			t.Fatalf("EvalValue(%q): %v", tt.input, err)
+		}
+		if got := v.String(); got != tt.want {
+			t.Errorf("EvalValue(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestRangeTooLong(t *testing.T) {
+	env := map[string]int{"lo": math.MinInt, "hi": math.MaxInt, "zero": 0}
+	for _, input := range []string{"lo..hi", "hi..lo", "zero..hi", "lo..zero", "0..1000000", "1000000..0"} {
+		expr, err := Parse(input)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", input, err)
+		}
+		_, err = EvaluateValue(expr, env)
+		if err == nil || !strings.Contains(err.Error(), "longer than") {
+			t.Errorf("EvaluateValue(%q) error = %v, want a length error", input, err)
+		}
+	}
+}
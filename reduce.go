@@ -0,0 +1,44 @@
+package main
+
+import "fmt"
+
+// EvaluateToAST evaluates expr like EvaluateValue and returns the result as a
+// literal node spanning expr, so that 2*3 + 4 reduces to the IntegerLiteral
+// 10 with the position of the whole sum. Floats and strings become
+// FloatLiteral and StringLiteral nodes, and sequences become an
+// ExpressionList of literals. A bool, or an integer too big for an int, has
+// no literal form and is an error.
+func EvaluateToAST(expr Expression, env map[string]int) (Expression, error) {
+	v, err := EvaluateValue(expr, env)
+	if err != nil {
+		return nil, err
+	}
+	return valueLiteral(v, expr.Pos(), expr.End())
+}
+
+func valueLiteral(v Value, pos, end Position) (Expression, error) {
+	switch v.Kind {
+	case IntValue:
+		return &IntegerLiteral{Value: v.Int, Position: pos, EndPosition: end}, nil
+	case BigValue:
+		if !v.Big.IsInt64() || int64(int(v.Big.Int64())) != v.Big.Int64() {
+			return nil, fmt.Errorf("result %s does not fit in an integer literal", v.Big)
+		}
+		return &IntegerLiteral{Value: int(v.Big.Int64()), Position: pos, EndPosition: end}, nil
+	case FloatValue:
+		return &FloatLiteral{Value: v.Float, Position: pos, EndPosition: end}, nil
+	case StringValue:
+		return &StringLiteral{Value: v.Str, Position: pos, EndPosition: end}, nil
+	case SeqValue:
+		list := &ExpressionList{Elements: make([]Expression, len(v.Seq)), Position: pos, EndPosition: end}
+		for i, elem := range v.Seq {
+			lit, err := valueLiteral(elem, pos, end)
+			if err != nil {
+				return nil, err
+			}
+			list.Elements[i] = lit
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("%s result %s has no literal form", v.Kind, v.Quote())
+}
@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestEvaluateToAST(t *testing.T) {
+	expr := mustParse(t, "2*3+4")
+	got, err := EvaluateToAST(expr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	il, ok := got.(*IntegerLiteral)
+	if !ok || il.Value != 10 {
+		t.Fatalf("EvaluateToAST(2*3+4) = %#v, want IntegerLiteral{Value: 10}", got)
+	}
+	if il.Pos() != expr.Pos() || il.End() != expr.End() {
+		t.Errorf("result spans %s-%s, want %s-%s", il.Pos(), il.End(), expr.Pos(), expr.End())
+	}
+}
+
+func TestEvaluateToASTKinds(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"x / 2.0", "3.5"},
+		{`"a" + "b"`, `"ab"`},
+		{"1..3", "(1, 2, 3)"},
+		{"x * x", "49"},
+	}
+	for _, tt := range tests {
+		got, err := EvaluateToAST(mustParse(t, tt.input), map[string]int{"x": 7})
+		if err != nil || got.String() != tt.want {
+			t.Errorf("EvaluateToAST(%q) = %v, %v, want %s", tt.input, got, err, tt.want)
+		}
+	}
+	for _, input := range []string{"1 < 2", "30!", "1 / 0"} {
+		if got, err := EvaluateToAST(mustParse(t, input), nil); err == nil {
+			t.Errorf("EvaluateToAST(%q) = %s, want an error", input, got)
+		}
+	}
+}
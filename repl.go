@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	prompt             = "> "
+	continuationPrompt = "... "
+)
+
+// repl reads input a line at a time, evaluating each program as soon as it is
+// complete and printing its value to out. Definitions persist from one
+// program to the next. While the input so far ends in the middle of an
+// expression, as "1 +" or "f(1," do, further lines are joined to it under a
+// continuation prompt. Errors are printed and the session carries on.
+func repl(in io.Reader, out io.Writer) error {
+	s := &session{ev: newEvaluator(nil), base: 10}
+	scanner := bufio.NewScanner(in)
+	pending := ""
+	for {
+		if pending == "" {
+			fmt.Fprint(out, prompt)
+		} else {
+			fmt.Fprint(out, continuationPrompt)
+		}
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+
+		line := scanner.Text()
+		if pending == "" && strings.HasPrefix(line, ":") {
+			if quit := s.command(out, line); quit {
+				return nil
+			}
+			continue
+		}
+
+		pending += line + "\n"
+		program, err := ParseProgram(pending)
+		if incomplete(err) {
+			continue
+		}
+		pending = ""
+		if err != nil {
+			fmt.Fprintln(out, "error:", err)
+			continue
+		}
+
+		v, err := s.ev.run(program)
+		if err != nil {
+			fmt.Fprintln(out, "error:", err)
+		} else if hasExpression(program) {
+			fmt.Fprintln(out, formatInBase(v, s.base))
+		}
+	}
+}
+
+const replHelp = `Enter an expression or statements separated by semicolons, such as
+  x = 6; def square(n) = n * n; square(x) + 1
+Commands:
+  :help     show this message
+  :vars     list variables and their values
+  :base N   print integers in base N: 2, 8, 10 or 16
+  :quit     end the session
+`
+
+// session is the state of a REPL that lasts from one input to the next.
+type session struct {
+	ev   *evaluator
+	base int // for printing integer results
+}
+
+// command runs a line starting with ':', reporting whether it ends the
+// session.
+func (s *session) command(out io.Writer, line string) (quit bool) {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case ":quit":
+		return true
+	case ":help":
+		fmt.Fprint(out, replHelp)
+	case ":vars":
+		for _, name := range s.ev.env.Names() {
+			v, _ := s.ev.env.Get(name)
+			fmt.Fprintf(out, "%s = %s\n", name, v.Quote())
+		}
+	case ":base":
+		if len(fields) == 1 {
+			fmt.Fprintf(out, "base %d\n", s.base)
+			break
+		}
+		base, err := strconv.Atoi(fields[1])
+		if _, ok := basePrefixes[base]; err != nil || len(fields) > 2 || (!ok && base != 10) {
+			fmt.Fprintf(out, "error: invalid base %s, want 2, 8, 10 or 16\n", strings.Join(fields[1:], " "))
+			break
+		}
+		s.base = base
+	default:
+		fmt.Fprintf(out, "error: unknown command %s, try :help\n", line)
+	}
+	return false
+}
+
+// incomplete reports whether the only thing wrong with a program that failed
+// to parse with err is that it ends mid-statement.
+func incomplete(err error) bool {
+	var list ErrorList
+	if errors.As(err, &list) && len(list) > 1 {
+		return false
+	}
+	return errors.Is(err, ErrUnexpectedEOF)
+}
+
+// basePrefixes are the literal prefixes of the bases other than 10 that
+// integers can be written in.
+var basePrefixes = map[int]string{2: "0b", 8: "0o", 16: "0x"}
+
+// formatInBase formats v like v.String, except that an integer is written in
+// base with its literal prefix, such as 0b111, so that it can be typed back in.
+func formatInBase(v Value, base int) string {
+	prefix, ok := basePrefixes[base]
+	if !ok || !v.isInteger() {
+		return v.String()
+	}
+	n := v.bigInt()
+	if n.Sign() < 0 {
+		return "-" + prefix + new(big.Int).Neg(n).Text(base)
+	}
+	return prefix + n.Text(base)
+}
+
+// hasExpression reports whether p does more than define functions.
+func hasExpression(p *Program) bool {
+	for _, stmt := range p.Statements {
+		if _, ok := stmt.(*FunctionDefinition); !ok {
+			return true
+		}
+	}
+	return false
+}
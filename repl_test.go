@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestREPLVars(t *testing.T) {
+	var out strings.Builder
+	if err := repl(strings.NewReader("x = 6\ny = x * 7\n:vars\n:quit\n1\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	want := "> 6\n> 42\n> x = 6\ny = 42\n> "
+	if got := out.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestREPLCommands(t *testing.T) {
+	var out strings.Builder
+	if err := repl(strings.NewReader(":help\n:nope\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	if !strings.Contains(got, replHelp) {
+		t.Errorf("output %q does not contain the help text", got)
+	}
+	if !strings.Contains(got, "error: unknown command :nope, try :help\n") {
+		t.Errorf("output %q does not report the unknown command", got)
+	}
+}
+
+func TestREPLContinuation(t *testing.T) {
+	var out strings.Builder
+	if err := repl(strings.NewReader("1 +\n2\n(4\n* 5\n)\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	want := "> ... 3\n> ... ... 20\n> \n"
+	if got := out.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestParseErrUnexpectedEOF(t *testing.T) {
+	for _, input := range []string{"1 +", "(1", "f(1,"} {
+		if _, err := Parse(input); !errors.Is(err, ErrUnexpectedEOF) {
+			t.Errorf("Parse(%q) error = %v, want ErrUnexpectedEOF", input, err)
+		}
+	}
+	if _, err := Parse("1 + * 2"); errors.Is(err, ErrUnexpectedEOF) {
+		t.Errorf("Parse(1 + * 2) error = %v, want a plain syntax error", err)
+	}
+}
+
+func TestREPLBase(t *testing.T) {
+	var out strings.Builder
+	input := ":base 2\n5+2\n:base 16\n255\n-255\n0.5\n:base\n:base 3\n:base 10\n7\n"
+	if err := repl(strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+	want := "> > 0b111\n> > 0xff\n> -0xff\n> 0.5\n> base 16\n> error: invalid base 3, want 2, 8, 10 or 16\n> > 7\n> \n"
+	if got := out.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
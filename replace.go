@@ -0,0 +1,78 @@
+package main
+
+// Replace returns a copy of n in which every node for which match reports
+// true is swapped for replace(node). The tree is walked from the root down
+// and the replacement is not itself walked, so replace may return a tree that
+// contains the node it was given. Nodes that are not replaced are copied as
+// by Clone. Below the root, replace must return an Expression.
+func Replace(n Node, match func(Node) bool, replace func(Node) Node) Node {
+	if n == nil {
+		return nil
+	}
+	if match(n) {
+		return replace(n)
+	}
+
+	r := func(expr Expression) Expression {
+		if expr == nil {
+			return nil
+		}
+		return Replace(expr, match, replace).(Expression)
+	}
+	switch e := n.(type) {
+	case *Program:
+		return &Program{Statements: replaceAll(e.Statements, r)}
+	case *BinaryExpression:
+		copied := *e
+		copied.Left = r(e.Left)
+		copied.Right = r(e.Right)
+		return &copied
+	case *NaryExpression:
+		copied := *e
+		copied.Operands = replaceAll(e.Operands, r)
+		return &copied
+	case *UnaryExpression:
+		copied := *e
+		copied.Operand = r(e.Operand)
+		return &copied
+	case *CastExpression:
+		copied := *e
+		copied.Operand = r(e.Operand)
+		return &copied
+	case *RangeExpression:
+		copied := *e
+		copied.From = r(e.From)
+		copied.To = r(e.To)
+		return &copied
+	case *ExpressionList:
+		copied := *e
+		copied.Elements = replaceAll(e.Elements, r)
+		return &copied
+	case *CallExpression:
+		copied := *e
+		copied.Arguments = replaceAll(e.Arguments, r)
+		return &copied
+	case *Assignment:
+		copied := *e
+		copied.Value = r(e.Value)
+		return &copied
+	case *FunctionDefinition:
+		copied := *e
+		copied.Parameters = append([]string(nil), e.Parameters...)
+		copied.Body = r(e.Body)
+		return &copied
+	default:
+		return Clone(n)
+	}
+}
+
+func replaceAll(exprs []Expression, r func(Expression) Expression) []Expression {
+	if exprs == nil {
+		return nil
+	}
+	out := make([]Expression, len(exprs))
+	for i, expr := range exprs {
+		out[i] = r(expr)
+	}
+	return out
+}
@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func isInteger(n Node) bool {
+	_, ok := n.(*IntegerLiteral)
+	return ok
+}
+
+func doubled(n Node) Node {
+	il := *n.(*IntegerLiteral)
+	il.Value *= 2
+	return &il
+}
+
+func TestReplaceDoublesIntegers(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1 + 2 * 3", "(2 + (4 * 6))"},
+		{"f(x, 5) - -1", "(f(x, 10) - (-2))"},
+		{"1.5 + x", "(1.5 + x)"},
+		{"7", "14"},
+	}
+	for _, tt := range tests {
+		expr := mustParse(t, tt.input)
+		before := expr.String()
+		if got := Replace(expr, isInteger, doubled).String(); got != tt.want {
+			t.Errorf("Replace(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+		if expr.String() != before {
+			t.Errorf("Replace modified its input %q to %s", tt.input, expr)
+		}
+	}
+}
+
+func TestReplaceDoesNotWalkReplacement(t *testing.T) {
+	isX := func(n Node) bool {
+		id, ok := n.(*Identifier)
+		return ok && id.Name == "x"
+	}
+	xPlusOne := func(n Node) Node {
+		return &BinaryExpression{Op: ADD, Left: n.(Expression), Right: &IntegerLiteral{Value: 1}}
+	}
+	if got := Replace(mustParse(t, "x * y"), isX, xPlusOne).String(); got != "((x + 1) * y)" {
+		t.Errorf("Replace = %s, want ((x + 1) * y)", got)
+	}
+}
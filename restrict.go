@@ -0,0 +1,72 @@
+package main
+
+import "fmt"
+
+// CheckOperators reports the first operator in n that is not in allowed, for
+// restricted calculators that parse a formula and then reject, say, division
+// and modulo. Operators are the Op of binary and unary nodes, and DOTDOT for
+// ranges.
+func CheckOperators(n Node, allowed []Token) error {
+	ok := make(map[Token]bool, len(allowed))
+	for _, tok := range allowed {
+		ok[tok] = true
+	}
+
+	var err error
+	Inspect(n, func(n Node) bool {
+		if err != nil {
+			return false
+		}
+		var op Token
+		switch e := n.(type) {
+		case *BinaryExpression:
+			op = e.Op
+		case *UnaryExpression:
+			op = e.Op
+		case *RangeExpression:
+			op = DOTDOT
+		default:
+			return true
+		}
+		if !ok[op] {
+			pos := n.Pos()
+			err = fmt.Errorf("operator %s is not allowed in %s at %s", TokenName(op), n, pos)
+		}
+		return err == nil
+	})
+	return err
+}
+
+// CheckDivisors reports the first division or modulo in n whose divisor is a
+// literal zero, such as x / 0 or x % 0.0, at the position of the divisor. It
+// finds the mistake before evaluation, even where the dividend could never be
+// evaluated for want of a binding. Divisors that merely evaluate to zero, like
+// 2 - 2, are not reported.
+func CheckDivisors(n Node) error {
+	var err error
+	Inspect(n, func(n Node) bool {
+		if err != nil {
+			return false
+		}
+		if be, ok := n.(*BinaryExpression); ok && (be.Op == DIV || be.Op == MOD) && isZeroLiteral(be.Right) {
+			what := "division"
+			if be.Op == MOD {
+				what = "modulo"
+			}
+			pos := be.Right.Pos()
+			err = fmt.Errorf("%s by literal zero in %s at %s", what, be, pos)
+		}
+		return err == nil
+	})
+	return err
+}
+
+func isZeroLiteral(expr Expression) bool {
+	switch e := expr.(type) {
+	case *IntegerLiteral:
+		return e.Value == 0
+	case *FloatLiteral:
+		return e.Value == 0
+	}
+	return false
+}
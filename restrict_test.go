@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestCheckOperators(t *testing.T) {
+	allowed := []Token{ADD, SUB, MUL}
+	if err := CheckOperators(mustParse(t, "1 + 2 * -3"), allowed); err != nil {
+		t.Errorf("CheckOperators(1 + 2 * -3): %v", err)
+	}
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1/2", "operator / is not allowed in (1 / 2) at 1:1"},
+		{"1 + 7 % 2", "operator % is not allowed in (7 % 2) at 1:5"},
+		{"1..3", "operator .. is not allowed in (1..3) at 1:1"},
+	}
+	for _, tt := range tests {
+		err := CheckOperators(mustParse(t, tt.input), allowed)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("CheckOperators(%q) = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestCheckDivisors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1/0", "division by literal zero in (1 / 0) at 1:3"},
+		{"1 + x % 0", "modulo by literal zero in (x % 0) at 1:9"},
+		{"y / 0.0", "division by literal zero in (y / 0.0) at 1:5"},
+	}
+	for _, tt := range tests {
+		err := CheckDivisors(mustParse(t, tt.input))
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("CheckDivisors(%q) = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+	for _, input := range []string{"1/(2-2)", "x % y", "0 / 1"} {
+		if err := CheckDivisors(mustParse(t, input)); err != nil {
+			t.Errorf("CheckDivisors(%q) = %v, want nil", input, err)
+		}
+	}
+}
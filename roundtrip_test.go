@@ -0,0 +1,83 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// randomTree returns a random expression tree of at most depth levels whose
+// String form is valid input, covering every expression node the parser
+// produces.
+func randomTree(r *rand.Rand, depth int) Expression {
+	if depth <= 0 || r.Intn(5) == 0 {
+		switch r.Intn(5) {
+		case 0:
+			return &Identifier{Name: []string{"x", "y", "_t1", "inf"}[r.Intn(4)]}
+		case 1:
+			return &FloatLiteral{Value: []float64{0.5, 2.25, 1e-7, 123456.75, 0.1}[r.Intn(5)]}
+		case 2:
+			return &StringLiteral{Value: []string{"", "a", "say \"hi\"\n", "tab\tback\\slash"}[r.Intn(4)]}
+		}
+		return &IntegerLiteral{Value: r.Intn(1000)}
+	}
+
+	switch r.Intn(10) {
+	case 0:
+		return &UnaryExpression{Op: SUB, Operand: randomTree(r, depth-1)}
+	case 1:
+		return &UnaryExpression{Op: []Token{BANG, PERCENT}[r.Intn(2)], Operand: randomTree(r, depth-1), Postfix: true}
+	case 2:
+		return &CastExpression{Operand: randomTree(r, depth-1), Type: []string{"int", "float"}[r.Intn(2)]}
+	case 3:
+		return &RangeExpression{From: randomTree(r, depth-1), To: randomTree(r, depth-1)}
+	case 4:
+		args := make([]Expression, r.Intn(4))
+		for i := range args {
+			args[i] = randomTree(r, depth-1)
+		}
+		return &CallExpression{Function: []string{"f", "abs", "sum"}[r.Intn(3)], Arguments: args}
+	case 5:
+		// A list of one element would print as a grouping.
+		elems := make([]Expression, []int{0, 2, 3}[r.Intn(3)])
+		for i := range elems {
+			elems[i] = randomTree(r, depth-1)
+		}
+		return &ExpressionList{Elements: elems}
+	}
+	ops := []Token{ADD, SUB, MUL, DIV, MOD, POW, EQ, NEQ, LT, LE, GT, GE}
+	return &BinaryExpression{Left: randomTree(r, depth-1), Op: ops[r.Intn(len(ops))], Right: randomTree(r, depth-1)}
+}
+
+// checkRoundTrip fails t unless expr's String form parses back into a tree
+// Equal to expr.
+func checkRoundTrip(t *testing.T, expr Expression) {
+	t.Helper()
+	text := expr.String()
+	again, err := Parse(text)
+	if err != nil {
+		t.Fatalf("Parse(%q) of a String result: %v", text, err)
+	}
+	if !Equal(expr, again) {
+		t.Fatalf("%q re-parsed as %q", text, again)
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		checkRoundTrip(t, randomTree(r, 6))
+	}
+}
+
+// FuzzStringRoundTrip checks that re-parsing the String form of anything that
+// parses gives back an Equal tree.
+func FuzzStringRoundTrip(f *testing.F) {
+	for _, seed := range []string{"10 - 3 - 2", "2 ^ 3 ^ 2", "-2 ^ 2", "50% * 2", "10 % (-3)", "50% - 10", "|x - 1|", "[1, 2]", "1..3..5", "'a' + 1", "1_000 as float"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		if expr, err := Parse(input); err == nil {
+			checkRoundTrip(t, expr)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package main
+
+import "strings"
+
+// ToSExpr renders expr as a prefix S-expression, such as (+ 1 (* 2 3)) for
+// 1 + 2 * 3. Every operation is parenthesized with its operator first, so
+// the form is unambiguous and diffs line up node by node. Other nodes are
+// written with a leading keyword: (as int x), (.. 1 10), (list 1 2),
+// (call f x), (= x 1) and (def f (x) body). Literals and names are written
+// as by String.
+func ToSExpr(expr Expression) string {
+	var b strings.Builder
+	writeSExpr(&b, expr)
+	return b.String()
+}
+
+func writeSExpr(b *strings.Builder, expr Expression) {
+	list := func(head string, exprs ...Expression) {
+		b.WriteByte('(')
+		b.WriteString(head)
+		for _, e := range exprs {
+			b.WriteByte(' ')
+			writeSExpr(b, e)
+		}
+		b.WriteByte(')')
+	}
+
+	switch e := expr.(type) {
+	case *BinaryExpression:
+		list(e.Symbol(), e.Left, e.Right)
+	case *NaryExpression:
+		list(e.Symbol(), e.Operands...)
+	case *UnaryExpression:
+		// A postfix % has one operand where modulo has two, so the operator
+		// alone tells the two apart.
+		list(e.Symbol(), e.Operand)
+	case *CastExpression:
+		list("as "+e.Type, e.Operand)
+	case *RangeExpression:
+		list("..", e.From, e.To)
+	case *ExpressionList:
+		list("list", e.Elements...)
+	case *CallExpression:
+		list("call "+e.Function, e.Arguments...)
+	case *Assignment:
+		list("= "+e.Name, e.Value)
+	case *FunctionDefinition:
+		list("def "+e.Name+" ("+strings.Join(e.Parameters, " ")+")", e.Body)
+	case nil:
+		b.WriteString("()")
+	default:
+		b.WriteString(expr.String())
+	}
+}
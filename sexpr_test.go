@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestToSExpr(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1 + 2 * 3", "(+ 1 (* 2 3))"},
+		{"(1 + 2) * 3", "(* (+ 1 2) 3)"},
+		{"-x ^ 2", "(- (^ x 2))"},
+		{"10 - 3 - 2", "(- (- 10 3) 2)"},
+		{"5!", "(! 5)"},
+		{"x as int", "(as int x)"},
+		{"1..10", "(.. 1 10)"},
+		{"(1, 2.5)", "(list 1 2.5)"},
+		{"f(x, g(1 + y))", "(call f x (call g (+ 1 y)))"},
+		{`"a" + "b"`, `(+ "a" "b")`},
+	}
+	for _, tt := range tests {
+		expr := mustParse(t, tt.input)
+		before := expr.String()
+		if got := ToSExpr(expr); got != tt.want {
+			t.Errorf("ToSExpr(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+		if expr.String() != before {
+			t.Errorf("String of %q changed to %s", tt.input, expr)
+		}
+	}
+}
+
+func TestToSExprStatements(t *testing.T) {
+	program, err := ParseProgram("x = 1; def f(a, b) = a * b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"(= x 1)", "(def f (a b) (* a b))"}
+	for i, stmt := range program.Statements {
+		if got := ToSExpr(stmt); got != want[i] {
+			t.Errorf("ToSExpr(%s) = %s, want %s", stmt, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import "math/bits"
+
+// literalShift reports whether e is an integer literal equal to 1<<k for some
+// k > 0, and returns k.
+func literalShift(e Expression) (uint, bool) {
+	il, ok := e.(*IntegerLiteral)
+	if !ok || il.Value <= 1 || il.Value&(il.Value-1) != 0 {
+		return 0, false
+	}
+	return uint(bits.TrailingZeros(uint(il.Value))), true
+}
+
+// shiftOp computes left*2^k or left/2^k with shifts. The result is identical
+// to applyOp: multiplication wraps the same way, and division rounds toward
+// zero rather than toward negative infinity as a plain arithmetic shift
+// would.
+func shiftOp(op Token, left int, k uint) int {
+	if op == MUL {
+		return left << k
+	}
+	if left < 0 {
+		left += 1<<k - 1
+	}
+	return left >> k
+}
+
+// fastShift applies the power-of-two fast path to be if one applies, given
+// its already-evaluated operands.
+func fastShift(be *BinaryExpression, left, right Value) (Value, bool) {
+	if left.Kind != IntValue || right.Kind != IntValue {
+		return Value{}, false
+	}
+	if k, ok := literalShift(be.Right); ok && (be.Op == MUL || be.Op == DIV) {
+		return Value{Kind: IntValue, Int: shiftOp(be.Op, left.Int, k)}, true
+	}
+	if k, ok := literalShift(be.Left); ok && be.Op == MUL {
+		return Value{Kind: IntValue, Int: shiftOp(MUL, right.Int, k)}, true
+	}
+	return Value{}, false
+}
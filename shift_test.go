@@ -0,0 +1,66 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestShiftOpMatchesArithmetic(t *testing.T) {
+	inputs := []int{0, 1, -1, 2, -2, 3, -3, 7, -7, 8, -8, 1023, -1023, 1 << 40, -(1 << 40) - 1, math.MaxInt, math.MinInt, math.MinInt + 1}
+	for k := uint(1); k < 63; k++ {
+		for _, left := range inputs {
+			for _, op := range []Token{MUL, DIV} {
+				want, err := applyOp(op, left, 1<<k)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := shiftOp(op, left, k); got != want {
+					t.Errorf("shiftOp(%s, %d, %d) = %d, want %d", TokenName(op), left, k, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestLiteralShift(t *testing.T) {
+	tests := []struct {
+		value int
+		k     uint
+		ok    bool
+	}{
+		{1, 0, false},
+		{2, 1, true},
+		{8, 3, true},
+		{6, 0, false},
+		{0, 0, false},
+		{-4, 0, false},
+		{1 << 62, 62, true},
+	}
+	for _, tt := range tests {
+		k, ok := literalShift(&IntegerLiteral{Value: tt.value})
+		if k != tt.k || ok != tt.ok {
+			t.Errorf("literalShift(%d) = %d, %t, want %d, %t", tt.value, k, ok, tt.k, tt.ok)
+		}
+	}
+}
+
+func TestFastShiftEvaluation(t *testing.T) {
+	for _, input := range []string{"x * 4", "4 * x", "x / 4", "x / 8 * 2"} {
+		expr := mustParse(t, input)
+		for _, x := range []int{-9, -8, -1, 0, 1, 7, 9} {
+			env := map[string]int{"x": x}
+			got, err := evaluateExpression(expr, env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Compile applies operators without the fast path.
+			want, err := Compile(expr)(env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("%s with x = %d is %d, want %d", input, x, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+// Simplify returns a copy of expr with algebraic identities applied bottom-up:
+// x*1, 1*x, x/1, x^1, x+0, 0+x and x-0 become x, x*0, 0*x and x-x become 0,
+// x^0 becomes 1, and x+x becomes 2*x.
+//
+// Identifiers are assumed to be bound to integers, as they are in the
+// environments the evaluators take, except for the predeclared constants nan
+// and inf, which are floats and are left alone: inf - inf and nan * 0 are
+// NaN, not 0. An identity is only applied when it cannot change the outcome
+// of evaluation: x must be a numeric expression, and a rule that discards x
+// entirely additionally requires that x cannot fail, so x*0 is kept when x
+// contains a division, a call or a factorial.
+func Simplify(expr Expression) Expression {
+	switch e := expr.(type) {
+	case *BinaryExpression:
+		left, right := Simplify(e.Left), Simplify(e.Right)
+		if simplified := simplifyBinary(e, left, right); simplified != nil {
+			return simplified
+		}
+		return &BinaryExpression{Left: left, Op: e.Op, Right: right, Position: e.Position, EndPosition: e.EndPosition}
+	case *NaryExpression:
+		return &NaryExpression{Op: e.Op, Operands: simplifyAll(e.Operands), Position: e.Position, EndPosition: e.EndPosition}
+	case *UnaryExpression:
+		copied := *e
+		copied.Operand = Simplify(e.Operand)
+		return &copied
+	case *CastExpression:
+		copied := *e
+		copied.Operand = Simplify(e.Operand)
+		return &copied
+	case *RangeExpression:
+		copied := *e
+		copied.From = Simplify(e.From)
+		copied.To = Simplify(e.To)
+		return &copied
+	case *ExpressionList:
+		return &ExpressionList{Elements: simplifyAll(e.Elements), Position: e.Position, EndPosition: e.EndPosition}
+	case *CallExpression:
+		return &CallExpression{Function: e.Function, Arguments: simplifyAll(e.Arguments), Position: e.Position, EndPosition: e.EndPosition}
+	case *Assignment:
+		copied := *e
+		copied.Value = Simplify(e.Value)
+		return &copied
+	case *FunctionDefinition:
+		copied := *e
+		copied.Body = Simplify(e.Body)
+		return &copied
+	default:
+		return Substitute(expr, nil)
+	}
+}
+
+// simplifyBinary returns the simplified form of e with its operands replaced
+// by left and right, or nil if no identity applies.
+func simplifyBinary(e *BinaryExpression, left, right Expression) Expression {
+	zero := &IntegerLiteral{Value: 0, Position: e.Position, EndPosition: e.EndPosition}
+	switch e.Op {
+	case ADD:
+		if isIntLiteral(right, 0) && isNumeric(left) {
+			return left
+		}
+		if isIntLiteral(left, 0) && isNumeric(right) {
+			return right
+		}
+		if isNumeric(left) && Equal(left, right) {
+			two := &IntegerLiteral{Value: 2, Position: e.Position, EndPosition: e.EndPosition}
+			return &BinaryExpression{Left: two, Op: MUL, Right: left, Position: e.Position, EndPosition: e.EndPosition}
+		}
+	case SUB:
+		if isIntLiteral(right, 0) && isNumeric(left) {
+			return left
+		}
+		if isPure(left) && Equal(left, right) {
+			return zero
+		}
+	case MUL:
+		if isIntLiteral(right, 1) && isNumeric(left) {
+			return left
+		}
+		if isIntLiteral(left, 1) && isNumeric(right) {
+			return right
+		}
+		if (isIntLiteral(right, 0) && isPure(left)) || (isIntLiteral(left, 0) && isPure(right)) {
+			return zero
+		}
+	case DIV:
+		if isIntLiteral(right, 1) && isNumeric(left) {
+			return left
+		}
+	case POW:
+		if isIntLiteral(right, 1) && isNumeric(left) {
+			return left
+		}
+		if isIntLiteral(right, 0) && isPure(left) {
+			return &IntegerLiteral{Value: 1, Position: e.Position, EndPosition: e.EndPosition}
+		}
+	}
+	return nil
+}
+
+func simplifyAll(exprs []Expression) []Expression {
+	out := make([]Expression, len(exprs))
+	for i, expr := range exprs {
+		out[i] = Simplify(expr)
+	}
+	return out
+}
+
+func isIntLiteral(expr Expression, v int) bool {
+	il, ok := expr.(*IntegerLiteral)
+	return ok && il.Value == v
+}
+
+// isNumeric reports whether expr, if it evaluates at all, evaluates to a
+// number, so that adding 0 to it or multiplying it by 1 leaves it unchanged.
+func isNumeric(expr Expression) bool {
+	switch e := expr.(type) {
+	case *IntegerLiteral, *FloatLiteral, *CastExpression:
+		return true
+	case *Identifier:
+		return !isConstant(e)
+	case *UnaryExpression:
+		return isNumeric(e.Operand)
+	case *BinaryExpression:
+		switch e.Op {
+		case ADD, SUB, MUL, DIV, MOD, POW:
+			return isNumeric(e.Left) && isNumeric(e.Right)
+		}
+	}
+	return false
+}
+
+// isPure reports whether expr is integer arithmetic that always evaluates
+// without error, so that an identity may discard it.
+func isPure(expr Expression) bool {
+	switch e := expr.(type) {
+	case *IntegerLiteral:
+		return true
+	case *Identifier:
+		return !isConstant(e)
+	case *UnaryExpression:
+		return e.Op == SUB && !e.Postfix && isPure(e.Operand)
+	case *BinaryExpression:
+		switch e.Op {
+		case ADD, SUB, MUL:
+			return isPure(e.Left) && isPure(e.Right)
+		}
+	}
+	return false
+}
+
+// isConstant reports whether id names one of the predeclared constants.
+func isConstant(id *Identifier) bool {
+	_, ok := constants[id.Name]
+	return ok
+}
@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"x * 1", "x"},
+		{"1 * x", "x"},
+		{"x / 1", "x"},
+		{"x ^ 1", "x"},
+		{"x + 0", "x"},
+		{"0 + x", "x"},
+		{"x - 0", "x"},
+		{"x * 0", "0"},
+		{"0 * x", "0"},
+		{"x - x", "0"},
+		{"x ^ 0", "1"},
+		{"x + x", "(2 * x)"},
+		{"(x + 0) * 1", "x"},
+		{"x + y", "(x + y)"},
+		{"(x / y) * 0", "((x / y) * 0)"},
+		{"inf - inf", "(inf - inf)"},
+		{"nan - nan", "(nan - nan)"},
+		{"nan * 0", "(nan * 0)"},
+		{"inf * 0", "(inf * 0)"},
+		{"inf + 0", "(inf + 0)"},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.input)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.input, err)
+		}
+		if got := Simplify(expr).String(); got != tt.want {
+			t.Errorf("Simplify(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSimplifyLeavesInputIntact(t *testing.T) {
+	expr, err := Parse("x * 1 + 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := Clone(expr).(Expression)
+	Simplify(expr)
+	if !Equal(expr, before) {
+		t.Errorf("Simplify modified its input: %s", expr)
+	}
+}
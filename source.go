@@ -0,0 +1,12 @@
+package main
+
+// Source returns the text of src that n was parsed from. src must be the
+// input the lexer read; if the node's offsets fall outside it, Source
+// returns the empty string.
+func Source(n Node, src string) string {
+	start, end := n.Pos().offset, n.End().offset
+	if start < 0 || end > len(src) || start > end {
+		return ""
+	}
+	return src[start:end]
+}
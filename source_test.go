@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestSource(t *testing.T) {
+	src := "1 + (2 * x_1) - 3"
+	expr := mustParse(t, src)
+	if got := Source(expr, src); got != src {
+		t.Errorf("Source(whole) = %q, want %q", got, src)
+	}
+
+	// ((1 + (2 * x_1)) - 3): the grouped product is the right operand of +.
+	sum := expr.(*BinaryExpression).Left.(*BinaryExpression)
+	if got, want := Source(sum, src), "1 + (2 * x_1)"; got != want {
+		t.Errorf("Source(sum) = %q, want %q", got, want)
+	}
+	product := sum.Right.(*BinaryExpression)
+	if got, want := Source(product, src), "2 * x_1"; got != want {
+		t.Errorf("Source(product) = %q, want %q", got, want)
+	}
+	if got, want := Source(product.Right, src), "x_1"; got != want {
+		t.Errorf("Source(identifier) = %q, want %q", got, want)
+	}
+}
+
+func TestSourceOutOfRange(t *testing.T) {
+	expr := mustParse(t, "123 + 456")
+	if got := Source(expr, "123"); got != "" {
+		t.Errorf("Source with a short input = %q, want \"\"", got)
+	}
+}
+
+func mustParse(t *testing.T, input string) Expression {
+	t.Helper()
+	expr, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", input, err)
+	}
+	return expr
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bufio"
+	"io"
+)
+
+// EvaluateStream evaluates the program read from r one statement at a time,
+// without building a Program, and passes the value of each expression or
+// assignment to yield as soon as it is evaluated. Memory use is bounded by
+// the largest statement rather than the whole input. Evaluation stops at the
+// first error, whether from parsing, evaluating, reading r, or yield itself.
+func EvaluateStream(r io.Reader, env map[string]int, yield func(Value) error) error {
+	l := NewLexer(bufio.NewReader(r))
+	p := newParser(l)
+	ev := newEvaluator(env)
+	for {
+		stmt, err := parseStatement(p)
+		if l.Err() != nil {
+			return l.Err()
+		}
+		if err != nil {
+			return err
+		}
+		if stmt == nil {
+			return nil
+		}
+
+		v, ok, err := ev.exec(stmt)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if err := yield(v); err != nil {
+				return err
+			}
+		}
+	}
+}
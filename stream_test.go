@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEvaluateStream(t *testing.T) {
+	var got []string
+	err := EvaluateStream(strings.NewReader("1 + 1; x = 10; x * 3; 0.5; 1 < 2"), nil, func(v Value) error {
+		got = append(got, v.String())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"2", "10", "30", "0.5", "true"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
+
+func TestEvaluateStreamStops(t *testing.T) {
+	var got []string
+	err := EvaluateStream(strings.NewReader("1; 2 / 0; 3"), nil, func(v Value) error {
+		got = append(got, v.String())
+		return nil
+	})
+	if err == nil || len(got) != 1 {
+		t.Errorf("results = %v, %v, want [1] and a division error", got, err)
+	}
+
+	stop := errors.New("stop")
+	got = nil
+	err = EvaluateStream(strings.NewReader("1; 2; 3"), nil, func(v Value) error {
+		got = append(got, v.String())
+		return stop
+	})
+	if err != stop || len(got) != 1 {
+		t.Errorf("results = %v, %v, want [1] and %v", got, err, stop)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+// Substitute returns a copy of expr in which every Identifier with an entry in
+// bindings is replaced by the bound expression. Unbound identifiers are kept.
+// Bound expressions are inserted as-is, so a binding used more than once is
+// shared between the places it appears.
+func Substitute(expr Expression, bindings map[string]Expression) Expression {
+	switch e := expr.(type) {
+	case *BinaryExpression:
+		return &BinaryExpression{
+			Left:        Substitute(e.Left, bindings),
+			Op:          e.Op,
+			Right:       Substitute(e.Right, bindings),
+			Position:    e.Position,
+			EndPosition: e.EndPosition,
+		}
+	case *NaryExpression:
+		return &NaryExpression{Op: e.Op, Operands: substituteAll(e.Operands, bindings), Position: e.Position, EndPosition: e.EndPosition}
+	case *UnaryExpression:
+		copied := *e
+		copied.Operand = Substitute(e.Operand, bindings)
+		return &copied
+	case *CastExpression:
+		copied := *e
+		copied.Operand = Substitute(e.Operand, bindings)
+		return &copied
+	case *RangeExpression:
+		copied := *e
+		copied.From = Substitute(e.From, bindings)
+		copied.To = Substitute(e.To, bindings)
+		return &copied
+	case *ExpressionList:
+		return &ExpressionList{Elements: substituteAll(e.Elements, bindings), Position: e.Position, EndPosition: e.EndPosition}
+	case *CallExpression:
+		return &CallExpression{Function: e.Function, Arguments: substituteAll(e.Arguments, bindings), Position: e.Position, EndPosition: e.EndPosition}
+	case *Assignment:
+		copied := *e
+		copied.Value = Substitute(e.Value, bindings)
+		return &copied
+	case *FunctionDefinition:
+		// Parameters shadow any bindings of the same name inside the body.
+		inner := make(map[string]Expression, len(bindings))
+		for name, bound := range bindings {
+			inner[name] = bound
+		}
+		for _, param := range e.Parameters {
+			delete(inner, param)
+		}
+		copied := *e
+		copied.Body = Substitute(e.Body, inner)
+		return &copied
+	case *Identifier:
+		if bound, ok := bindings[e.Name]; ok {
+			return bound
+		}
+		copied := *e
+		return &copied
+	case *IntegerLiteral:
+		copied := *e
+		return &copied
+	case *FloatLiteral:
+		copied := *e
+		return &copied
+	case *StringLiteral:
+		copied := *e
+		return &copied
+	default:
+		return expr
+	}
+}
+
+func substituteAll(exprs []Expression, bindings map[string]Expression) []Expression {
+	out := make([]Expression, len(exprs))
+	for i, expr := range exprs {
+		out[i] = Substitute(expr, bindings)
+	}
+	return out
+}
@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestSubstitute(t *testing.T) {
+	expr := mustParse(t, "x * 2")
+	got := Substitute(expr, map[string]Expression{"x": mustParse(t, "(1+1)")})
+	if want := "((1 + 1) * 2)"; got.String() != want {
+		t.Errorf("Substitute = %s, want %s", got, want)
+	}
+	folded, err := EvaluateToAST(got, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(folded, &IntegerLiteral{Value: 4}) {
+		t.Errorf("folded = %s, want 4", folded)
+	}
+	if expr.String() != "(x * 2)" {
+		t.Errorf("Substitute modified its input: %s", expr)
+	}
+}
+
+func TestSubstituteKeepsUnbound(t *testing.T) {
+	expr := mustParse(t, "x + y * x")
+	got := Substitute(expr, map[string]Expression{"x": mustParse(t, "a+b")})
+	if want := "((a + b) + (y * (a + b)))"; got.String() != want {
+		t.Errorf("Substitute = %s, want %s", got, want)
+	}
+}
+
+func TestSubstituteShadowedParameter(t *testing.T) {
+	def, err := UnmarshalText([]byte("def f(x) = x + y"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Substitute(def, map[string]Expression{"x": &IntegerLiteral{Value: 1}, "y": &IntegerLiteral{Value: 2}})
+	if want := "def f(x) = (x + 2)"; got.String() != want {
+		t.Errorf("Substitute = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+)
+
+// MarshalText implements encoding.TextMarshaler using the String form, which
+// UnmarshalText parses back into an equal tree.
+func (be *BinaryExpression) MarshalText() ([]byte, error) {
+	return []byte(be.String()), nil
+}
+
+// MarshalText implements encoding.TextMarshaler. UnmarshalText parses the
+// result back into the chain Unflatten would produce.
+func (ne *NaryExpression) MarshalText() ([]byte, error) {
+	return []byte(ne.String()), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (ue *UnaryExpression) MarshalText() ([]byte, error) {
+	return []byte(ue.String()), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (il *IntegerLiteral) MarshalText() ([]byte, error) {
+	return []byte(il.String()), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (fl *FloatLiteral) MarshalText() ([]byte, error) {
+	return []byte(fl.String()), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (ce *CastExpression) MarshalText() ([]byte, error) {
+	return []byte(ce.String()), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (re *RangeExpression) MarshalText() ([]byte, error) {
+	return []byte(re.String()), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (id *Identifier) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (sl *StringLiteral) MarshalText() ([]byte, error) {
+	return []byte(sl.String()), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (el *ExpressionList) MarshalText() ([]byte, error) {
+	return []byte(el.String()), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (ce *CallExpression) MarshalText() ([]byte, error) {
+	return []byte(ce.String()), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (a *Assignment) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (fd *FunctionDefinition) MarshalText() ([]byte, error) {
+	return []byte(fd.String()), nil
+}
+
+// UnmarshalText parses text produced by MarshalText back into an Expression.
+// It accepts a single statement, so that assignments and function
+// definitions round-trip along with plain expressions; a semicolon may end
+// the statement but nothing may follow it.
+func UnmarshalText(text []byte) (Expression, error) {
+	l := NewLexer(bufio.NewReader(bytes.NewReader(text)))
+	p := newParser(l)
+	stmt, err := parseStatement(p)
+	if err == nil {
+		if pos, tok, lit := p.Peek(); stmt == nil || tok != EOF {
+			err = unexpected(pos, tok, lit)
+		}
+	}
+	if l.Err() != nil {
+		// The lexer ended the input early, which explains any parse error.
+		return nil, l.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
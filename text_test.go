@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding"
+	"encoding/json"
+	"testing"
+)
+
+func TestMarshalTextRoundTrip(t *testing.T) {
+	for _, input := range []string{"1 + 2 * 3", "(1 + 2) * 3", "10 - 3 - 2", "-x ^ 2", "f(1, y) % 4", "\"a\" + \"b\"", "1.5 as int", "1..3", "5!"} {
+		expr := mustParse(t, input)
+		text, err := expr.(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText(%s): %v", expr, err)
+		}
+		again, err := UnmarshalText(text)
+		if err != nil {
+			t.Fatalf("UnmarshalText(%q): %v", text, err)
+		}
+		if !Equal(expr, again) {
+			t.Errorf("%q re-parsed from %q as %s", input, text, again)
+		}
+
+		// The canonical form is stable.
+		if again, _ := again.(encoding.TextMarshaler).MarshalText(); string(again) != string(text) {
+			t.Errorf("MarshalText of %q = %q, want %q", text, again, text)
+		}
+	}
+}
+
+func TestMarshalTextJSON(t *testing.T) {
+	config := struct{ Formula Expression }{mustParse(t, "x * (y + 1)")}
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"Formula":"(x * (y + 1))"}`; string(data) != want {
+		t.Errorf("json.Marshal = %s, want %s", data, want)
+	}
+}
+
+func TestUnmarshalTextStatements(t *testing.T) {
+	for _, input := range []string{
+		"x = 1 + 2",
+		"let y = 2 * z",
+		"def square(x) = x * x",
+		"def zero() = 0;",
+	} {
+		stmt, err := UnmarshalText([]byte(input))
+		if err != nil {
+			t.Fatalf("UnmarshalText(%q): %v", input, err)
+		}
+		text, err := stmt.(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText(%s): %v", stmt, err)
+		}
+		again, err := UnmarshalText(text)
+		if err != nil {
+			t.Fatalf("UnmarshalText(%q): %v", text, err)
+		}
+		if !Equal(stmt, again) {
+			t.Errorf("%q re-parsed from %q as %s", input, text, again)
+		}
+	}
+}
+
+func TestUnmarshalTextSingleStatement(t *testing.T) {
+	for _, input := range []string{"", ";", "1; 2", "x = 1; x"} {
+		if stmt, err := UnmarshalText([]byte(input)); err == nil {
+			t.Errorf("UnmarshalText(%q) = %s, want an error", input, stmt)
+		}
+	}
+}
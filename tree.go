@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatTree renders n as an indented outline, one node per line with its
+// children indented beneath it. It is meant for debugging the parser.
+func FormatTree(n Node) string {
+	var b strings.Builder
+	formatTree(&b, n, 0)
+	return b.String()
+}
+
+func formatTree(b *strings.Builder, n Node, depth int) {
+	line := func(format string, args ...any) {
+		b.WriteString(strings.Repeat("  ", depth))
+		fmt.Fprintf(b, format, args...)
+		b.WriteByte('\n')
+	}
+	children := func(nodes ...Expression) {
+		for _, child := range nodes {
+			formatTree(b, child, depth+1)
+		}
+	}
+
+	switch n := n.(type) {
+	case *Program:
+		line("Program")
+		children(n.Statements...)
+	case *BinaryExpression:
+		line("BinaryExpression %s", n.Symbol())
+		children(n.Left, n.Right)
+	case *NaryExpression:
+		line("NaryExpression %s", n.Symbol())
+		children(n.Operands...)
+	case *UnaryExpression:
+		if n.Postfix {
+			line("UnaryExpression postfix %s", n.Symbol())
+		} else {
+			line("UnaryExpression %s", n.Symbol())
+		}
+		children(n.Operand)
+	case *CastExpression:
+		line("CastExpression %s", n.Type)
+		children(n.Operand)
+	case *RangeExpression:
+		line("RangeExpression")
+		children(n.From, n.To)
+	case *ExpressionList:
+		line("ExpressionList")
+		children(n.Elements...)
+	case *CallExpression:
+		line("CallExpression %s", n.Function)
+		children(n.Arguments...)
+	case *Assignment:
+		line("Assignment %s", n.Name)
+		children(n.Value)
+	case *FunctionDefinition:
+		line("FunctionDefinition %s(%s)", n.Name, strings.Join(n.Parameters, ", "))
+		children(n.Body)
+	case *IntegerLiteral:
+		line("IntegerLiteral %s", n)
+	case *FloatLiteral:
+		line("FloatLiteral %s", n)
+	case *StringLiteral:
+		line("StringLiteral %s", n)
+	case *Identifier:
+		line("Identifier %s", n.Name)
+	default:
+		line("%T", n)
+	}
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ValueKind identifies which field of a Value holds its payload.
+type ValueKind int
+
+const (
+	IntValue ValueKind = iota
+	StringValue
+	BigValue
+	FloatValue
+	SeqValue
+	BoolValue
+)
+
+var valueKinds = []string{
+	IntValue:    "int",
+	StringValue: "string",
+	BigValue:    "bigint",
+	FloatValue:  "float",
+	SeqValue:    "sequence",
+	BoolValue:   "bool",
+}
+
+func (k ValueKind) String() string {
+	return valueKinds[k]
+}
+
+// Value is the result of evaluating an expression.
+type Value struct {
+	Kind  ValueKind
+	Int   int
+	Str   string
+	Big   *big.Int
+	Float float64
+	Seq   []Value
+	Bool  bool
+}
+
+// String formats v for display; strings are shown without quotes.
+func (v Value) String() string {
+	switch v.Kind {
+	case StringValue:
+		return v.Str
+	case BigValue:
+		return v.Big.String()
+	case FloatValue:
+		return formatFloat(v.Float)
+	case SeqValue:
+		elems := make([]string, len(v.Seq))
+		for i, elem := range v.Seq {
+			elems[i] = elem.Quote()
+		}
+		return "(" + strings.Join(elems, ", ") + ")"
+	case BoolValue:
+		return strconv.FormatBool(v.Bool)
+	}
+	return strconv.Itoa(v.Int)
+}
+
+// Quote formats v as it would be written in the source, for error messages.
+func (v Value) Quote() string {
+	if v.Kind == StringValue {
+		return (&StringLiteral{Value: v.Str}).String()
+	}
+	return v.String()
+}
+
+// EvaluateValue evaluates expr, resolving identifiers in env. Unlike
+// evaluateExpression it also handles strings: + concatenates two strings,
+// and any other mix of strings and numbers is an error.
+func EvaluateValue(expr Expression, env map[string]int) (Value, error) {
+	return newEvaluator(env).eval(expr)
+}
+
+// EvalValue parses input as a program and returns the value of its last
+// expression, of whatever kind.
+func EvalValue(input string) (Value, error) {
+	p, err := ParseProgram(input)
+	if err != nil {
+		return Value{}, err
+	}
+	return EvaluateProgram(p, nil)
+}
+
+// Eval is EvalValue for callers that only expect integers: any other result
+// is an error.
+func Eval(input string) (int, error) {
+	v, err := EvalValue(input)
+	if err != nil {
+		return 0, err
+	}
+	return v.integer()
+}
+
+// MustEval is Eval for inputs known to be valid, such as test fixtures and
+// quick scripts: it panics if input fails to parse or evaluate to an int.
+// Code handling outside input should call Eval and check the error.
+func MustEval(input string) int {
+	n, err := Eval(input)
+	if err != nil {
+		panic(fmt.Sprintf("MustEval(%q): %v", input, err))
+	}
+	return n
+}
+
+// integer returns v as an int, failing if it is of another kind.
+func (v Value) integer() (int, error) {
+	if v.Kind != IntValue {
+		return 0, fmt.Errorf("expected an integer, got %s %s", v.Kind, v.Quote())
+	}
+	return v.Int, nil
+}
+
+// EvalOptions adjusts how EvaluateValueWith evaluates. The zero value gives
+// the same results as EvaluateValue.
+type EvalOptions struct {
+	// BigInt makes factorial produce exact arbitrary-precision integers
+	// instead of failing once the result no longer fits in an int.
+	BigInt bool
+
+	// LenientVars makes an undefined variable evaluate to 0 instead of
+	// being an error, as in a spreadsheet.
+	LenientVars bool
+
+	// OSEnv makes variables that are not otherwise bound fall back to
+	// environment variables of the same name, which must hold integers.
+	OSEnv bool
+
+	// Clamp enables saturating integer arithmetic: the result of every
+	// arithmetic operation on ints is computed exactly and then clamped to
+	// [ClampMin, ClampMax] rather than wrapping on overflow. As without
+	// Clamp, an int raised to a negative power is a float and is not
+	// clamped. ClampMin must not exceed ClampMax.
+	Clamp    bool
+	ClampMin int
+	ClampMax int
+
+	// MaxDepth bounds nested function calls. Zero means maxCallDepth.
+	MaxDepth int
+
+	// DivByZero chooses what dividing by zero, or taking a remainder
+	// modulo zero, produces.
+	DivByZero DivByZeroPolicy
+
+	// ExactDivision makes integer division an error when it would leave a
+	// remainder, so that 7 / 2 fails instead of truncating to 3.
+	ExactDivision bool
+}
+
+// DivByZeroPolicy is what evaluation does on division or modulo by zero.
+type DivByZeroPolicy int
+
+const (
+	// DivByZeroError makes division by zero an error.
+	DivByZeroError DivByZeroPolicy = iota
+	// DivByZeroZero makes division by zero evaluate to zero, as some
+	// spreadsheets and proof assistants define it.
+	DivByZeroZero
+)
+
+// EvaluateValueWith is like EvaluateValue but configured by opts.
+func EvaluateValueWith(expr Expression, env map[string]int, opts EvalOptions) (Value, error) {
+	if opts.Clamp && opts.ClampMin > opts.ClampMax {
+		return Value{}, fmt.Errorf("clamp bounds %d and %d are inverted", opts.ClampMin, opts.ClampMax)
+	}
+	ev := newEvaluator(env)
+	ev.opts = opts
+	return ev.eval(expr)
+}
+
+// maxCallDepth bounds nested function calls so runaway recursion fails
+// cleanly instead of exhausting the Go stack.
+const maxCallDepth = 1000
+
+type evaluator struct {
+	env   *Environment
+	depth int
+	opts  EvalOptions
+
+	// ctx, if set, is checked before every node and while building long
+	// ranges, and evaluation fails with ctx.Err() once it is done.
+	ctx context.Context
+}
+
+func newEvaluator(vars map[string]int) *evaluator {
+	env := NewEnvironment(nil)
+	for name, n := range vars {
+		env.Set(name, Value{Kind: IntValue, Int: n})
+	}
+	return &evaluator{env: env}
+}
+
+func (ev *evaluator) eval(expr Expression) (Value, error) {
+	if err := ev.interrupted(); err != nil {
+		return Value{}, err
+	}
+
+	switch e := expr.(type) {
+	case *BinaryExpression:
+		left, err := ev.eval(e.Left)
+		if err != nil {
+			return Value{}, err
+		}
+
+		right, err := ev.eval(e.Right)
+		if err != nil {
+			return Value{}, err
+		}
+		return ev.binary(e, left, right)
+
+	case *UnaryExpression:
+		operand, err := ev.eval(e.Operand)
+		if err != nil {
+			return Value{}, err
+		}
+
+		switch e.Op {
+		case BANG:
+			return ev.factorial(operand)
+		case PERCENT:
+			if !operand.isNumber() {
+				return Value{}, fmt.Errorf("cannot take a percentage of %s %s", operand.Kind, operand.Quote())
+			}
+			return Value{Kind: FloatValue, Float: operand.float() / 100}, nil
+		case SUB:
+			if ev.opts.Clamp && operand.Kind == IntValue {
+				return ev.clamp(new(big.Int).Neg(operand.bigInt())), nil
+			}
+			return negate(operand)
+		}
+		return Value{}, fmt.Errorf("unknown operator")
+
+	case *IntegerLiteral:
+		return Value{Kind: IntValue, Int: e.Value}, nil
+
+	case *FloatLiteral:
+		return Value{Kind: FloatValue, Float: e.Value}, nil
+
+	case *StringLiteral:
+		return Value{Kind: StringValue, Str: e.Value}, nil
+
+	case *CastExpression:
+		operand, err := ev.eval(e.Operand)
+		if err != nil {
+			return Value{}, err
+		}
+		return cast(operand, e.Type)
+
+	case *Identifier:
+		return ev.lookup(e.Name)
+
+	case *CallExpression:
+		return ev.call(e)
+
+	case *ExpressionList:
+		seq := make([]Value, len(e.Elements))
+		for i, elem := range e.Elements {
+			v, err := ev.eval(elem)
+			if err != nil {
+				return Value{}, err
+			}
+			seq[i] = v
+		}
+		return Value{Kind: SeqValue, Seq: seq}, nil
+
+	case *RangeExpression:
+		return ev.evalRange(e)
+
+	case *NaryExpression:
+		if len(e.Operands) == 0 {
+			return Value{}, fmt.Errorf("%s with no operands", e.Symbol())
+		}
+		return ev.eval(Unflatten(e))
+
+	case *FunctionDefinition:
+		return Value{}, fmt.Errorf("function definition %s is only allowed as a statement", e.Name)
+
+	case *Assignment:
+		return Value{}, fmt.Errorf("assignment to %s is only allowed as a statement", e.Name)
+
+	default:
+		return Value{}, fmt.Errorf("unknown expression type")
+	}
+}
+
+// interrupted returns ctx.Err() if the evaluator has a context.
+func (ev *evaluator) interrupted() error {
+	if ev.ctx == nil {
+		return nil
+	}
+	return ev.ctx.Err()
+}
+
+// binary applies e's operator to its already-evaluated operands. The
+// evaluators that walk the tree their own way call it too, so that every
+// evaluator agrees on results and errors.
+func (ev *evaluator) binary(e *BinaryExpression, left, right Value) (Value, error) {
+	if custom, ok := binaryOps[e.Op]; ok {
+		return custom.fn(left, right)
+	}
+	if ev.opts.DivByZero == DivByZeroZero && (e.Op == DIV || e.Op == MOD) && left.isNumber() && right.isZero() {
+		if left.Kind == FloatValue || right.Kind == FloatValue {
+			return Value{Kind: FloatValue}, nil
+		}
+		return Value{Kind: IntValue}, nil
+	}
+	if ev.opts.ExactDivision && e.Op == DIV && left.isInteger() && right.isInteger() && !right.isZero() {
+		if new(big.Int).Rem(left.bigInt(), right.bigInt()).Sign() != 0 {
+			return Value{}, fmt.Errorf("non-exact division %s / %s at %s", left.Quote(), right.Quote(), e.Position)
+		}
+	}
+	if ev.opts.Clamp && !isComparisonOp(e.Op) && left.Kind == IntValue && right.Kind == IntValue && !(e.Op == POW && right.Int < 0) {
+		v, err := applyBigOp(e.Op, left.bigInt(), right.bigInt())
+		if err != nil {
+			return Value{}, err
+		}
+		return ev.clamp(v.Big), nil
+	}
+	if v, ok := fastShift(e, left, right); ok {
+		return v, nil
+	}
+	return applyValueOp(e.Op, left, right)
+}
+
+// clamp saturates n to the configured range.
+func (ev *evaluator) clamp(n *big.Int) Value {
+	switch lo, hi := big.NewInt(int64(ev.opts.ClampMin)), big.NewInt(int64(ev.opts.ClampMax)); {
+	case n.Cmp(lo) < 0:
+		return Value{Kind: IntValue, Int: ev.opts.ClampMin}
+	case n.Cmp(hi) > 0:
+		return Value{Kind: IntValue, Int: ev.opts.ClampMax}
+	}
+	return Value{Kind: IntValue, Int: int(n.Int64())}
+}
+
+// lookup resolves a variable through the scope chain and then whichever
+// fallbacks the options enable.
+func (ev *evaluator) lookup(name string) (Value, error) {
+	if v, ok := ev.env.Get(name); ok {
+		return v, nil
+	}
+	if v, ok := constants[name]; ok {
+		return v, nil
+	}
+	if ev.opts.OSEnv {
+		if s, ok := os.LookupEnv(name); ok {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				return Value{}, fmt.Errorf("environment variable %s=%q is not an integer", name, s)
+			}
+			return Value{Kind: IntValue, Int: n}, nil
+		}
+	}
+	if ev.opts.LenientVars {
+		return Value{Kind: IntValue}, nil
+	}
+	return Value{}, fmt.Errorf("undefined variable %s", name)
+}
+
+// call evaluates the arguments in the caller's scope, then the function body
+// in a new scope for the parameters nested inside the function's definition
+// scope.
+func (ev *evaluator) call(c *CallExpression) (Value, error) {
+	fn, ok := ev.env.lookupFunc(c.Function)
+	if !ok {
+		if c.Function == "if" {
+			return ev.callIf(c)
+		}
+		if builtin, ok := builtins[c.Function]; ok {
+			return ev.callBuiltin(c, builtin)
+		}
+		return Value{}, fmt.Errorf("undefined function %s", c.Function)
+	}
+	def := fn.def
+	if len(c.Arguments) != len(def.Parameters) {
+		return Value{}, fmt.Errorf("%s expects %d %s, got %d", def.Name, len(def.Parameters), plural(len(def.Parameters), "argument"), len(c.Arguments))
+	}
+	maxDepth := ev.opts.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = maxCallDepth
+	}
+	if ev.depth >= maxDepth {
+		return Value{}, fmt.Errorf("maximum call depth of %d exceeded calling %s", maxDepth, def.Name)
+	}
+
+	scope := NewEnvironment(fn.env)
+	for i, arg := range c.Arguments {
+		v, err := ev.eval(arg)
+		if err != nil {
+			return Value{}, err
+		}
+		scope.Set(def.Parameters[i], v)
+	}
+	return (&evaluator{env: scope, depth: ev.depth + 1, opts: ev.opts, ctx: ev.ctx}).eval(def.Body)
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+func negate(v Value) (Value, error) {
+	switch v.Kind {
+	case IntValue:
+		return Value{Kind: IntValue, Int: -v.Int}, nil
+	case BigValue:
+		return Value{Kind: BigValue, Big: new(big.Int).Neg(v.Big)}, nil
+	case FloatValue:
+		return Value{Kind: FloatValue, Float: -v.Float}, nil
+	}
+	return Value{}, fmt.Errorf("cannot negate %s %s", v.Kind, v.Quote())
+}
+
+func applyValueOp(op Token, left, right Value) (Value, error) {
+	if isComparisonOp(op) {
+		return compare(op, left, right)
+	}
+	if isBigOperand(left, right) && isBigOperand(right, left) {
+		return applyBigOp(op, left.bigInt(), right.bigInt())
+	}
+	if isFloatOperand(left, right) && isFloatOperand(right, left) {
+		return applyFloatOp(op, left.float(), right.float())
+	}
+	if op == POW && left.Kind == IntValue && right.Kind == IntValue && right.Int < 0 {
+		// 2 ^ -1 is 0.5, not an error.
+		return applyFloatOp(op, left.float(), right.float())
+	}
+	if left.Kind != right.Kind {
+		return Value{}, fmt.Errorf("mismatched types %s and %s for %s", left.Kind, right.Kind, TokenName(op))
+	}
+
+	switch left.Kind {
+	case StringValue:
+		if op != ADD {
+			return Value{}, fmt.Errorf("operator %s not defined on strings", TokenName(op))
+		}
+		return Value{Kind: StringValue, Str: left.Str + right.Str}, nil
+
+	case IntValue:
+		n, err := applyOp(op, left.Int, right.Int)
+		if err != nil {
+			return Value{}, err
+		}
+		return Value{Kind: IntValue, Int: n}, nil
+
+	default:
+		return Value{}, fmt.Errorf("operator %s not defined on %s", TokenName(op), left.Kind)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import "testing"
+
+func TestStringConcatenation(t *testing.T) {
+	v, err := EvalValue(`"foo" + "bar" == "foobar"`)
+	if err != nil || v.Kind != BoolValue || !v.Bool {
+		t.Errorf(`EvalValue("foo" + "bar" == "foobar") = %s, %v, want true`, v, err)
+	}
+	v, err = EvalValue(`"foo" + "bar"`)
+	if err != nil || v.Kind != StringValue || v.Str != "foobar" {
+		t.Errorf(`EvalValue("foo" + "bar") = %s, %v, want "foobar"`, v.Quote(), err)
+	}
+}
+
+func TestStringTypeErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`"foo" + 1`, "mismatched types string and int for +"},
+		{`1 + "foo"`, "mismatched types int and string for +"},
+		{`"foo" * "bar"`, "operator * not defined on strings"},
+		{`"foo"`, `expected an integer, got string "foo"`},
+	}
+	for _, tt := range tests {
+		_, err := Eval(tt.input)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Eval(%s) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestLenientVars(t *testing.T) {
+	expr := mustParse(t, "x + 1")
+	if _, err := EvaluateValueWith(expr, nil, EvalOptions{}); err == nil || err.Error() != "undefined variable x" {
+		t.Errorf("strict x + 1 error = %v, want undefined variable x", err)
+	}
+	v, err := EvaluateValueWith(expr, nil, EvalOptions{LenientVars: true})
+	if err != nil || v.Int != 1 {
+		t.Errorf("lenient x + 1 = %s, %v, want 1", v, err)
+	}
+	v, err = EvaluateValueWith(expr, map[string]int{"x": 4}, EvalOptions{LenientVars: true})
+	if err != nil || v.Int != 5 {
+		t.Errorf("lenient x + 1 with x = 4 = %s, %v, want 5", v, err)
+	}
+}
+
+func TestOSEnv(t *testing.T) {
+	t.Setenv("LEXER_TEST_PORT", "8080")
+	t.Setenv("LEXER_TEST_HOST", "localhost")
+	expr := mustParse(t, "LEXER_TEST_PORT + 1")
+
+	if _, err := EvaluateValueWith(expr, nil, EvalOptions{}); err == nil {
+		t.Errorf("environment variables were read without OSEnv")
+	}
+	v, err := EvaluateValueWith(expr, nil, EvalOptions{OSEnv: true})
+	if err != nil || v.Int != 8081 {
+		t.Errorf("LEXER_TEST_PORT + 1 = %s, %v, want 8081", v, err)
+	}
+	v, err = EvaluateValueWith(expr, map[string]int{"LEXER_TEST_PORT": 1}, EvalOptions{OSEnv: true})
+	if err != nil || v.Int != 2 {
+		t.Errorf("a bound variable did not take precedence: got %s, %v, want 2", v, err)
+	}
+
+	_, err = EvaluateValueWith(mustParse(t, "LEXER_TEST_HOST"), nil, EvalOptions{OSEnv: true})
+	if want := `environment variable LEXER_TEST_HOST="localhost" is not an integer`; err == nil || err.Error() != want {
+		t.Errorf("non-integer variable error = %v, want %q", err, want)
+	}
+}
+
+func TestClamp(t *testing.T) {
+	opts := EvalOptions{Clamp: true, ClampMin: 0, ClampMax: 255}
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"200 + 100", 255},
+		{"100 - 200", 0},
+		{"16 * 16", 255},
+		{"200 + 100 - 100", 155},
+		{"-5", 0},
+		{"10 / 3", 3},
+	}
+	for _, tt := range tests {
+		v, err := EvaluateValueWith(mustParse(t, tt.input), nil, opts)
+		if err != nil || v.Kind != IntValue || v.Int != tt.want {
+			t.Errorf("%s clamped to [0, 255] = %s, %v, want %d", tt.input, v, err, tt.want)
+		}
+	}
+	if v, _ := Eval("200 + 100"); v != 300 {
+		t.Errorf("200 + 100 without Clamp = %d, want 300", v)
+	}
+}
+
+func TestClampNegativePower(t *testing.T) {
+	expr := mustParse(t, "2 ^ -1")
+	want, err := EvaluateValue(expr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := EvaluateValueWith(expr, nil, EvalOptions{Clamp: true, ClampMin: -255, ClampMax: 255})
+	if err != nil || v.Kind != want.Kind || v.String() != want.String() {
+		t.Errorf("2 ^ -1 clamped = %s, %v, want %s as without Clamp", v, err, want)
+	}
+}
+
+func TestClampInvertedBounds(t *testing.T) {
+	_, err := EvaluateValueWith(mustParse(t, "1 + 2"), nil, EvalOptions{Clamp: true, ClampMin: 10, ClampMax: 5})
+	if err == nil || err.Error() != "clamp bounds 10 and 5 are inverted" {
+		t.Errorf("inverted clamp bounds: got %v", err)
+	}
+	if _, err := Evaluate(mustParse(t, "1 + 2"), WithOptions(EvalOptions{Clamp: true, ClampMin: 10, ClampMax: 5})); err == nil {
+		t.Error("Evaluate with inverted clamp bounds succeeded")
+	}
+}
+
+func TestEvalValue(t *testing.T) {
+	tests := []struct {
+		input string
+		kind  ValueKind
+		want  string
+	}{
+		{"6 * 7", IntValue, "42"},
+		{"1 / 2.0", FloatValue, "0.5"},
+		{"2 > 1", BoolValue, "true"},
+		{"1 == 2", BoolValue, "false"},
+	}
+	for _, tt := range tests {
+		v, err := EvalValue(tt.input)
+		if err != nil || v.Kind != tt.kind || v.String() != tt.want {
+			t.Errorf("EvalValue(%q) = %s (kind %v), %v, want %s", tt.input, v, v.Kind, err, tt.want)
+		}
+	}
+}
+
+func TestEvalRequiresInt(t *testing.T) {
+	if n, err := Eval("6 * 7"); err != nil || n != 42 {
+		t.Errorf("Eval(6 * 7) = %d, %v, want 42", n, err)
+	}
+	for _, input := range []string{"1 / 2.0", "2 > 1"} {
+		if _, err := Eval(input); err == nil {
+			t.Errorf("Eval(%q) succeeded, want an error for a non-integer result", input)
+		}
+	}
+}
+
+func TestMustEval(t *testing.T) {
+	if got := MustEval("2+2"); got != 4 {
+		t.Errorf("MustEval(2+2) = %d, want 4", got)
+	}
+	for _, input := range []string{"1 +", "1 / 0", "0.5"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("MustEval(%q) did not panic", input)
+				}
+			}()
+			MustEval(input)
+		}()
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type opcode byte
+
+const (
+	opPush opcode = iota // push arg
+	opLoad               // push the variable name
+	opAdd
+	opSub
+	opMul
+	opDiv
+	opNeg
+	opEval // push evaluateExpression(expr)
+)
+
+var opcodes = []string{
+	opPush: "PUSH",
+	opLoad: "LOAD",
+	opAdd:  "ADD",
+	opSub:  "SUB",
+	opMul:  "MUL",
+	opDiv:  "DIV",
+	opNeg:  "NEG",
+	opEval: "EVAL",
+}
+
+type instruction struct {
+	op   opcode
+	arg  int
+	name string
+	expr Expression // the subtree the instruction came from
+}
+
+// Bytecode is an expression compiled for a stack machine by CompileBytecode.
+// It is immutable, so one Bytecode may be run concurrently.
+type Bytecode struct {
+	code     []instruction
+	maxStack int
+}
+
+// CompileBytecode compiles expr to stack bytecode. Integer arithmetic on
+// literals and variables becomes PUSH, LOAD and operator instructions; any
+// other subtree, including arithmetic with an operand that might not be an
+// integer, becomes a single EVAL instruction that defers to
+// evaluateExpression when the code runs.
+func CompileBytecode(expr Expression) *Bytecode {
+	b := &Bytecode{}
+	b.emit(expr, 0)
+	return b
+}
+
+// emit appends the code for expr, which will run with depth values already
+// on the stack.
+func (b *Bytecode) emit(expr Expression, depth int) {
+	switch e := expr.(type) {
+	case *BinaryExpression:
+		if op, ok := binaryOpcodes[e.Op]; ok && isIntegral(e) {
+			b.emit(e.Left, depth)
+			b.emit(e.Right, depth+1)
+			b.code = append(b.code, instruction{op: op, expr: e})
+			return
+		}
+	case *UnaryExpression:
+		if isIntegral(e) {
+			b.emit(e.Operand, depth)
+			b.code = append(b.code, instruction{op: opNeg, expr: e})
+			return
+		}
+	case *IntegerLiteral:
+		b.push(instruction{op: opPush, arg: e.Value, expr: e}, depth)
+		return
+	case *Identifier:
+		b.push(instruction{op: opLoad, name: e.Name, expr: e}, depth)
+		return
+	}
+	b.push(instruction{op: opEval, expr: expr}, depth)
+}
+
+func (b *Bytecode) push(in instruction, depth int) {
+	b.code = append(b.code, in)
+	if depth+1 > b.maxStack {
+		b.maxStack = depth + 1
+	}
+}
+
+var binaryOpcodes = map[Token]opcode{ADD: opAdd, SUB: opSub, MUL: opMul, DIV: opDiv}
+
+// Run executes the code with variables resolved in env. A division by zero is
+// reported at the position of the dividing expression.
+func (b *Bytecode) Run(env map[string]int) (int, error) {
+	stack := make([]int, 0, b.maxStack)
+	for _, in := range b.code {
+		switch in.op {
+		case opPush:
+			stack = append(stack, in.arg)
+		case opLoad:
+			v, ok := env[in.name]
+			if !ok {
+				var err error
+				if v, err = evaluateExpression(in.expr, env); err != nil {
+					return 0, err
+				}
+			}
+			stack = append(stack, v)
+		case opEval:
+			v, err := evaluateExpression(in.expr, env)
+			if err != nil {
+				return 0, err
+			}
+			stack = append(stack, v)
+		case opNeg:
+			stack[len(stack)-1] = -stack[len(stack)-1]
+		default:
+			l, r := stack[len(stack)-2], stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			switch in.op {
+			case opAdd:
+				stack[len(stack)-1] = l + r
+			case opSub:
+				stack[len(stack)-1] = l - r
+			case opMul:
+				stack[len(stack)-1] = l * r
+			case opDiv:
+				if r == 0 {
+					pos := in.expr.Pos()
+					return 0, fmt.Errorf("division by zero at %s", pos)
+				}
+				stack[len(stack)-1] = l / r
+			}
+		}
+	}
+	return stack[0], nil
+}
+
+// String disassembles the code, one instruction per line.
+func (b *Bytecode) String() string {
+	var sb strings.Builder
+	for i, in := range b.code {
+		fmt.Fprintf(&sb, "%d\t%s", i, opcodes[in.op])
+		switch in.op {
+		case opPush:
+			fmt.Fprintf(&sb, "\t%d", in.arg)
+		case opLoad:
+			fmt.Fprintf(&sb, "\t%s", in.name)
+		case opEval:
+			fmt.Fprintf(&sb, "\t%s", in.expr)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestBytecodeAgrees(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 500; i++ {
+		expr := randomExpression(r, 5)
+		code := CompileBytecode(expr)
+		for x := -3; x <= 3; x++ {
+			env := map[string]int{"x": x, "y": 7 - x}
+			want, wantErr := evaluateExpression(expr, env)
+			got, err := code.Run(env)
+			if (err == nil) != (wantErr == nil) || got != want {
+				t.Fatalf("Run(%s) with x = %d = %d, %v; evaluateExpression gives %d, %v\n%s", expr, x, got, err, want, wantErr, code)
+			}
+			// The VM adds the position to a division by zero.
+			if err != nil && !strings.HasPrefix(err.Error(), wantErr.Error()) {
+				t.Errorf("Run(%s) error = %v, want %v", expr, err, wantErr)
+			}
+		}
+	}
+}
+
+func TestBytecodeDivisionByZero(t *testing.T) {
+	code := CompileBytecode(mustParse(t, "1 + x / (2 - 2)"))
+	_, err := code.Run(map[string]int{"x": 4})
+	if want := "division by zero at 1:5"; err == nil || err.Error() != want {
+		t.Errorf("Run error = %v, want %q", err, want)
+	}
+}
+
+func TestBytecodeString(t *testing.T) {
+	code := CompileBytecode(mustParse(t, "-x * 2 + (y as int)"))
+	want := "0\tLOAD\tx\n1\tNEG\n2\tPUSH\t2\n3\tMUL\n4\tEVAL\t(y as int)\n5\tADD\n"
+	if got := code.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	// abs may return a float, so nothing around it can be compiled.
+	if got, want := CompileBytecode(mustParse(t, "1 + abs(y)")).String(), "0\tEVAL\t(1 + abs(y))\n"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if v, err := code.Run(map[string]int{"x": 3, "y": -4}); err != nil || v != -10 {
+		t.Errorf("Run = %d, %v, want -10", v, err)
+	}
+}
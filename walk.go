@@ -0,0 +1,41 @@
+package main
+
+// Inspect traverses the tree rooted at n in depth-first order, calling f for
+// each node. Like ast.Inspect, it skips a node's children when f returns false.
+func Inspect(n Node, f func(Node) bool) {
+	if n == nil || !f(n) {
+		return
+	}
+	switch e := n.(type) {
+	case *Program:
+		for _, stmt := range e.Statements {
+			Inspect(stmt, f)
+		}
+	case *BinaryExpression:
+		Inspect(e.Left, f)
+		Inspect(e.Right, f)
+	case *NaryExpression:
+		for _, operand := range e.Operands {
+			Inspect(operand, f)
+		}
+	case *UnaryExpression:
+		Inspect(e.Operand, f)
+	case *CastExpression:
+		Inspect(e.Operand, f)
+	case *RangeExpression:
+		Inspect(e.From, f)
+		Inspect(e.To, f)
+	case *ExpressionList:
+		for _, elem := range e.Elements {
+			Inspect(elem, f)
+		}
+	case *CallExpression:
+		for _, arg := range e.Arguments {
+			Inspect(arg, f)
+		}
+	case *Assignment:
+		Inspect(e.Value, f)
+	case *FunctionDefinition:
+		Inspect(e.Body, f)
+	}
+}